@@ -0,0 +1,177 @@
+package gadget
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// BuildContext selects the files of a package that are compiled together,
+// using build constraints like //go:build linux and file name suffixes like _linux_amd64.go.
+type BuildContext struct {
+	OS   string   // The target GOOS. Defaults to the GOOS gadget is running on.
+	Arch string   // The target GOARCH. Defaults to the GOARCH gadget is running on.
+	Tags []string // Additional build tags that are satisfied, like integration or cgo.
+}
+
+// BuildContext returns the BuildContext for the OS, Arch and Tags of the Info.
+func (i *Info) BuildContext() BuildContext {
+	return BuildContext{OS: i.OS, Arch: i.Arch, Tags: i.Tags}
+}
+
+// Match returns true if a file with the given path and build constraint expression is selected by the context.
+// The constraint is an expression as found in File.Constraint, like "linux && !arm"; an empty constraint is always satisfied.
+func (c BuildContext) Match(path string, expr string) (bool, error) {
+	if !c.matchFileName(path) {
+		return false, nil
+	}
+	if expr == "" {
+		return true, nil
+	}
+	parsed, err := constraint.Parse("//go:build " + expr)
+	if err != nil {
+		return false, fmt.Errorf("invalid build constraint '%s': %w", expr, err)
+	}
+	return parsed.Eval(c.matchTag), nil
+}
+
+// matchFileName returns true if the GOOS and GOARCH suffixes of the file name, if any, match the context.
+func (c BuildContext) matchFileName(path string) bool {
+	name := strings.TrimSuffix(filepath.Base(path), ".go")
+	name = strings.TrimSuffix(name, "_test")
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return true
+	}
+	parts := strings.Split(name[i:], "_")
+	n := len(parts)
+	if n >= 2 && knownOS[parts[n-2]] && knownArch[parts[n-1]] {
+		return c.matchTag(parts[n-2]) && c.matchTag(parts[n-1])
+	}
+	if knownOS[parts[n-1]] || knownArch[parts[n-1]] {
+		return c.matchTag(parts[n-1])
+	}
+	return true
+}
+
+// matchTag returns true if the build tag is satisfied by the context, the way go build decides.
+func (c BuildContext) matchTag(tag string) bool {
+	goos, goarch := c.OS, c.Arch
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	for _, t := range c.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	switch {
+	case tag == goos, tag == goarch, tag == runtime.Compiler:
+		return true
+	case tag == "linux" && goos == "android":
+		return true
+	case tag == "solaris" && goos == "illumos":
+		return true
+	case tag == "darwin" && goos == "ios":
+		return true
+	case tag == "unix" && unixOS[goos]:
+		return true
+	case strings.HasPrefix(tag, "go1."):
+		return releaseTag(tag)
+	}
+	return false
+}
+
+// releaseTag returns true if the Go release tag, like go1.18, is satisfied by the Go version gadget is built with.
+// Development versions satisfy all release tags.
+func releaseTag(tag string) bool {
+	var minor int
+	if _, err := fmt.Sscanf(tag, "go1.%d", &minor); err != nil || fmt.Sprintf("go1.%d", minor) != tag {
+		return false
+	}
+	var current int
+	if _, err := fmt.Sscanf(runtime.Version(), "go1.%d", &current); err != nil {
+		return true
+	}
+	return minor <= current
+}
+
+// fileConstraint returns the build constraint in the comments before the package clause.
+// A //go:build line takes precedence over // +build lines, which are combined the way go build does.
+func fileConstraint(parsedFile *ast.File) (constraint.Expr, error) {
+	var plusBuild constraint.Expr
+	for _, group := range parsedFile.Comments {
+		if group.Pos() >= parsedFile.Package {
+			break
+		}
+		for _, comment := range group.List {
+			switch {
+			case constraint.IsGoBuild(comment.Text):
+				expr, err := constraint.Parse(comment.Text)
+				if err != nil {
+					return nil, fmt.Errorf("invalid build constraint: %w", err)
+				}
+				return expr, nil
+			case constraint.IsPlusBuild(comment.Text):
+				expr, err := constraint.Parse(comment.Text)
+				if err != nil {
+					return nil, fmt.Errorf("invalid build constraint: %w", err)
+				}
+				if plusBuild == nil {
+					plusBuild = expr
+					continue
+				}
+				plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+			}
+		}
+	}
+	return plusBuild, nil
+}
+
+// readHeader reads the package name and build constraint of the Go file at path, without parsing the rest of it.
+func readHeader(path string) (string, string, error) {
+	parsedFile, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse file '%s': %w", path, err)
+	}
+	expr, err := fileConstraint(parsedFile)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", path, err)
+	}
+	return parsedFile.Name.Name, constraintString(expr), nil
+}
+
+func constraintString(expr constraint.Expr) string {
+	if expr == nil {
+		return ""
+	}
+	return expr.String()
+}
+
+// knownOS and knownArch are the GOOS and GOARCH values recognized in file name suffixes.
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+	"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true, "openbsd": true,
+	"plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true, "arm64be": true,
+	"loong64": true, "mips": true, "mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true,
+	"mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true, "riscv": true, "riscv64": true,
+	"s390": true, "s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+}
+
+// unixOS are the GOOS values satisfying the unix build tag.
+var unixOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+	"illumos": true, "ios": true, "linux": true, "netbsd": true, "openbsd": true, "solaris": true,
+}
@@ -0,0 +1,175 @@
+package gadget
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildContext_Match(t *testing.T) {
+	linux := BuildContext{OS: "linux", Arch: "amd64", Tags: []string{"integration"}}
+	android := BuildContext{OS: "android", Arch: "arm64"}
+	for _, test := range []struct {
+		ctx        BuildContext
+		path       string
+		constraint string
+		want       bool
+	}{
+		{linux, "file.go", "", true},
+		{linux, "file_linux.go", "", true},
+		{linux, "file_windows.go", "", false},
+		{linux, "file_linux_amd64.go", "", true},
+		{linux, "file_linux_arm64.go", "", false},
+		{linux, "file_amd64_test.go", "", true},
+		{linux, "linux.go", "", true},
+		{linux, "file_other.go", "", true},
+		{linux, "file.go", "linux && !arm64", true},
+		{linux, "file.go", "windows || darwin", false},
+		{linux, "file.go", "unix", true},
+		{linux, "file.go", "integration", true},
+		{linux, "file.go", "ignore", false},
+		{linux, "file.go", "go1.1", true},
+		{linux, "file.go", "go1.9999", false},
+		{android, "file_linux.go", "linux && arm64", true},
+		{android, "file_amd64.go", "", false},
+	} {
+		got, err := test.ctx.Match(test.path, test.constraint)
+		if err != nil {
+			t.Fatalf("%s %q: %v", test.path, test.constraint, err)
+		}
+		if got != test.want {
+			t.Fatalf("%s/%s: expected %v for %s with constraint %q, got %v", test.ctx.OS, test.ctx.Arch, test.want, test.path, test.constraint, got)
+		}
+	}
+	if _, err := linux.Match("file.go", "linux &&"); err == nil {
+		t.Fatalf("expected error for invalid constraint")
+	}
+}
+
+func TestNewFile_Constraint(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		want string
+	}{
+		{"package test\n", ""},
+		{"//go:build linux && (amd64 || arm64)\n\npackage test\n", "linux && (amd64 || arm64)"},
+		{"// +build linux darwin\n// +build amd64\n\npackage test\n", "(linux || darwin) && amd64"},
+		{"//go:build windows\n// +build windows\n\npackage test\n", "windows"},
+		{"// Package test has a doc comment.\npackage test\n\n//go:build linux\n", ""},
+	} {
+		f, err := NewFile("test.go", strings.NewReader(test.src))
+		if err != nil {
+			t.Fatalf("failed to parse file: %v", err)
+		}
+		if f.Constraint != test.want {
+			t.Fatalf("expected constraint %q, got %q in:\n%s", test.want, f.Constraint, test.src)
+		}
+	}
+	if _, err := NewFile("test.go", strings.NewReader("//go:build linux &&\n\npackage test\n")); err == nil {
+		t.Fatalf("expected error for invalid constraint")
+	}
+}
+
+func TestNewPackageFor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gadget")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"common.go":         "package test\n\ntype Common struct{}\n",
+		"impl_linux.go":     "package test\n\ntype Impl struct{ fd int }\n",
+		"impl_windows.go":   "package test\n\ntype Impl struct{ handle uintptr }\n",
+		"tagged.go":         "//go:build integration\n\npackage test\n\ntype Tagged struct{}\n",
+		"ignored.go":        "//go:build ignore\n\npackage main\n\nfunc main() {}\n",
+		"unix.go":           "//go:build unix\n\npackage test\n\ntype Unix struct{}\n",
+		"common_test.go":    "package test\n",
+		"impl_plan9_386.go": "package test\n\ntype Impl struct{}\n",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	for _, test := range []struct {
+		ctx  BuildContext
+		want []string
+	}{
+		{BuildContext{OS: "linux", Arch: "amd64"}, []string{"common.go", "impl_linux.go", "unix.go"}},
+		{BuildContext{OS: "windows", Arch: "amd64", Tags: []string{"integration"}}, []string{"common.go", "impl_windows.go", "tagged.go"}},
+		{BuildContext{OS: "plan9", Arch: "386"}, []string{"common.go", "impl_plan9_386.go"}},
+	} {
+		p, err := NewPackageFor(dir, "test", test.ctx)
+		if err != nil {
+			t.Fatalf("failed to load package: %v", err)
+		}
+		var got []string
+		for _, f := range p.Files {
+			got = append(got, filepath.Base(f.Path))
+		}
+		if !reflect.DeepEqual(test.want, got) {
+			t.Logf("want: %v", test.want)
+			t.Logf(" got: %v", got)
+			t.Fatalf("invalid files for %s/%s", test.ctx.OS, test.ctx.Arch)
+		}
+	}
+	p, err := NewPackage(dir, "test")
+	if err != nil {
+		t.Fatalf("failed to load package: %v", err)
+	}
+	if len(p.Files) != 6 {
+		t.Fatalf("expected NewPackage to ignore constraints, got %d files", len(p.Files))
+	}
+	if f := p.File(filepath.Join(dir, "tagged.go")); f == nil || f.Constraint != "integration" {
+		t.Fatalf("expected constraint of tagged.go, got %#v", f)
+	}
+
+	for _, test := range []struct {
+		file string
+		tags []string
+		want []string
+	}{
+		{"tagged.go", nil, []string{"common.go", "impl_linux.go", "tagged.go", "unix.go"}},
+		{"common_test.go", nil, []string{"common.go", "common_test.go", "impl_linux.go", "unix.go"}},
+		{"common.go", []string{"integration"}, []string{"common.go", "impl_linux.go", "tagged.go", "unix.go"}},
+	} {
+		info := &Info{OS: "linux", Arch: "amd64", Package: "test", File: filepath.Join(dir, test.file), Tags: test.tags}
+		p, err := info.OpenPackage()
+		if err != nil {
+			t.Fatalf("failed to open package for %s: %v", test.file, err)
+		}
+		var got []string
+		for _, f := range p.Files {
+			got = append(got, filepath.Base(f.Path))
+		}
+		if !reflect.DeepEqual(test.want, got) {
+			t.Logf("want: %v", test.want)
+			t.Logf(" got: %v", got)
+			t.Fatalf("invalid files opened for %s", test.file)
+		}
+	}
+}
+
+func TestOutput_Constraint(t *testing.T) {
+	o := NewOutput("gen.go", "test", nil)
+	o.Constraint = "linux && amd64"
+	o.Printf("var x int\n")
+	data, err := o.Bytes()
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	f, err := NewFile("gen.go", strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("failed to parse generated file: %v", err)
+	}
+	if f.Constraint != o.Constraint {
+		t.Fatalf("expected constraint %q, got %q in:\n%s", o.Constraint, f.Constraint, data)
+	}
+	o.Constraint = "linux &&"
+	if _, err := o.Bytes(); err == nil {
+		t.Fatalf("expected error for invalid constraint")
+	}
+}
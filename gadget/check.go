@@ -0,0 +1,363 @@
+package gadget
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// ObjectKind is the kind of object an identifier refers to.
+type ObjectKind int
+
+const (
+	UnknownObject ObjectKind = iota
+	ConstObject
+	VarObject
+	TypeObject
+	FuncObject
+	PackageObject
+	BuiltinObject
+	NilObject
+)
+
+func (k ObjectKind) String() string {
+	switch k {
+	case ConstObject:
+		return "CONST"
+	case VarObject:
+		return "VAR"
+	case TypeObject:
+		return "TYPE"
+	case FuncObject:
+		return "FUNC"
+	case PackageObject:
+		return "PACKAGE"
+	case BuiltinObject:
+		return "BUILTIN"
+	case NilObject:
+		return "NIL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Object describes a package level identifier, as resolved by the type checker.
+type Object struct {
+	Kind       ObjectKind
+	Name       string
+	Package    string         // The import path of the declaring package. Empty for predeclared identifiers.
+	Type       Type           // The type of the object. For TypeObject, this is the named type itself.
+	Underlying Type           // The underlying type of Type.
+	Value      constant.Value // The value of a ConstObject. Nil otherwise.
+}
+
+// Annotation describes a type, as resolved by the type checker.
+type Annotation struct {
+	Type       types.Type // The go/types representation of the type.
+	Underlying Type       // The underlying type.
+	Named      bool       // Named is true if the type is a named type, including predeclared types like int.
+	Package    string     // The import path of the package declaring a named type. Empty for predeclared and unnamed types.
+}
+
+// Checked is a Package that has been type checked using go/types.
+// It answers the questions gadget's syntax-only model can not, like what the underlying type of a named type is.
+type Checked struct {
+	Package *Package
+	Types   *types.Package // The type checked package.
+	Info    *types.Info    // The type information recorded while checking.
+	Errors  []error        // The type errors encountered. Checking continues past errors.
+
+	// Qualifier names the package of a type declared in another package, like the Left of the Selector time.Duration.
+	// Defaults to the package name. Set it to Output.Import to write the types to an Output without resolving them
+	// through the imports of a file.
+	Qualifier types.Qualifier
+
+	fileSet *token.FileSet
+	files   []*ast.File
+}
+
+// Check type checks the package with go/types.
+// The package is checked under its ImportPath, or its Name if that is empty.
+// Imports are loaded from source, so no compiled export data is needed.
+// Type errors, like references to code that has yet to be generated, do not fail Check; they are recorded in Checked.Errors.
+func (p *Package) Check() (*Checked, error) {
+	c := &Checked{
+		Package: p,
+		Info: &types.Info{
+			Types: make(map[ast.Expr]types.TypeAndValue),
+			Defs:  make(map[*ast.Ident]types.Object),
+			Uses:  make(map[*ast.Ident]types.Object),
+		},
+		fileSet: p.fileSet,
+	}
+	if !p.parsed() {
+		// The files were not parsed together, so parse them again into a single file set.
+		c.fileSet = token.NewFileSet()
+		for _, f := range p.Files {
+			parsedFile, err := parser.ParseFile(c.fileSet, f.Path, nil, parser.ParseComments)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse file '%s': %w", f.Path, err)
+			}
+			c.files = append(c.files, parsedFile)
+		}
+	} else {
+		c.files = p.syntax
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(c.fileSet, "source", nil),
+		Error: func(err error) {
+			c.Errors = append(c.Errors, err)
+		},
+	}
+	path := p.ImportPath
+	if path == "" {
+		path = p.Name
+	}
+	c.Types, _ = conf.Check(path, c.fileSet, c.files, c.Info)
+	return c, nil
+}
+
+// parsed returns true if the syntax trees of the files are still those parsed by NewPackage.
+func (p *Package) parsed() bool {
+	if p.fileSet == nil || len(p.syntax) != len(p.Files) {
+		return false
+	}
+	for i, f := range p.Files {
+		if p.fileSet.Position(p.syntax[i].Pos()).Filename != f.Path {
+			return false
+		}
+	}
+	return true
+}
+
+// Lookup resolves a package level identifier, or a predeclared identifier like int or nil.
+func (c *Checked) Lookup(name string) (Object, error) {
+	_, obj := c.Types.Scope().LookupParent(name, token.NoPos)
+	if obj == nil {
+		return Object{}, fmt.Errorf("undeclared identifier '%s'", name)
+	}
+	o := Object{
+		Name: name,
+	}
+	if obj.Pkg() != nil {
+		o.Package = obj.Pkg().Path()
+	}
+	switch obj := obj.(type) {
+	case *types.Const:
+		o.Kind = ConstObject
+		o.Value = obj.Val()
+	case *types.Var:
+		o.Kind = VarObject
+	case *types.TypeName:
+		o.Kind = TypeObject
+	case *types.Func:
+		o.Kind = FuncObject
+	case *types.PkgName:
+		o.Kind = PackageObject
+		o.Package = obj.Imported().Path()
+		return o, nil
+	case *types.Builtin:
+		o.Kind = BuiltinObject
+		return o, nil
+	case *types.Nil:
+		o.Kind = NilObject
+		return o, nil
+	}
+	o.Type = c.fromTypes(obj.Type())
+	o.Underlying = c.fromTypes(obj.Type().Underlying())
+	return o, nil
+}
+
+// Annotate resolves t as seen from the file containing pos.
+// Only the Path of pos is used; the imports of that file are in scope.
+func (c *Checked) Annotate(pos Position, t Type) (Annotation, error) {
+	typ, err := c.typeAt(pos.Path, t)
+	if err != nil {
+		return Annotation{}, err
+	}
+	return c.annotate(typ), nil
+}
+
+// Underlying returns the underlying type of t.
+// Selectors are resolved using the imports of the first file of the package that can resolve them.
+func (c *Checked) Underlying(t Type) (Type, error) {
+	var lastErr error
+	for _, f := range c.Package.Files {
+		typ, err := c.typeAt(f.Path, t)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return c.fromTypes(typ.Underlying()), nil
+	}
+	return nil, lastErr
+}
+
+// InterfaceMethods returns the complete method set of the interface type t as seen from the file containing pos,
+// including the methods of embedded interfaces declared in other packages. The methods are sorted by name.
+func (c *Checked) InterfaceMethods(pos Position, t Type) ([]Method, error) {
+	typ, err := c.typeAt(pos.Path, t)
+	if err != nil {
+		return nil, err
+	}
+	iface, ok := typ.Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an interface", t)
+	}
+	methods := make([]Method, iface.NumMethods())
+	for i := range methods {
+		method := iface.Method(i)
+		methods[i] = Method{Name: method.Name(), Type: c.fromSignature(method.Type().(*types.Signature))}
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
+	return methods, nil
+}
+
+func (c *Checked) annotate(typ types.Type) Annotation {
+	a := Annotation{
+		Type:       typ,
+		Underlying: c.fromTypes(typ.Underlying()),
+	}
+	switch named := types.Unalias(typ).(type) {
+	case *types.Basic:
+		a.Named = true
+	case *types.Named:
+		a.Named = true
+		if pkg := named.Obj().Pkg(); pkg != nil {
+			a.Package = pkg.Path()
+		}
+	}
+	return a
+}
+
+// typeAt evaluates t as a type expression in the scope of the file at path.
+func (c *Checked) typeAt(path string, t Type) (types.Type, error) {
+	var file *ast.File
+	for _, f := range c.files {
+		if c.fileSet.Position(f.Pos()).Filename == path {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return nil, fmt.Errorf("file '%s' is not part of package '%s'", path, c.Package.Name)
+	}
+	// The end of the package clause is inside the file scope, unlike the end of a file without a trailing newline.
+	tv, err := types.Eval(c.fileSet, c.Types, file.Name.End(), t.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate type %s: %w", t, err)
+	}
+	if !tv.IsType() {
+		return nil, fmt.Errorf("%s is not a type", t)
+	}
+	return tv.Type, nil
+}
+
+// qualify returns the name of a package other than the checked one, using Qualifier if it is set.
+func (c *Checked) qualify(pkg *types.Package) string {
+	if c.Qualifier != nil {
+		return c.Qualifier(pkg)
+	}
+	return pkg.Name()
+}
+
+// fromTypes converts a go/types type to a Type.
+// Named types from other packages become Selectors, qualified by qualify.
+func (c *Checked) fromTypes(typ types.Type) Type {
+	switch t := types.Unalias(typ).(type) {
+	case *types.Basic:
+		return Ident(t.Name())
+	case *types.Named:
+		obj := t.Obj()
+		var named Type = Ident(obj.Name())
+		if obj.Pkg() != nil && obj.Pkg() != c.Types {
+			named = Selector{Left: Ident(c.qualify(obj.Pkg())), Right: Ident(obj.Name())}
+		}
+		if args := t.TypeArgs(); args != nil && args.Len() > 0 {
+			inst := Instance{Type: named}
+			for i := 0; i < args.Len(); i++ {
+				inst.Args = append(inst.Args, c.fromTypes(args.At(i)))
+			}
+			return inst
+		}
+		return named
+	case *types.TypeParam:
+		return TypeParam(t.Obj().Name())
+	case *types.Pointer:
+		return Pointer{Elem: c.fromTypes(t.Elem())}
+	case *types.Slice:
+		return Slice{Elem: c.fromTypes(t.Elem())}
+	case *types.Array:
+		return Array{Elem: c.fromTypes(t.Elem()), Size: int(t.Len())}
+	case *types.Map:
+		return Map{Key: c.fromTypes(t.Key()), Value: c.fromTypes(t.Elem())}
+	case *types.Chan:
+		dir := BOTH
+		switch t.Dir() {
+		case types.SendOnly:
+			dir = SEND
+		case types.RecvOnly:
+			dir = RECV
+		}
+		return Chan{Dir: dir, Elem: c.fromTypes(t.Elem())}
+	case *types.Struct:
+		var s Struct
+		for i := 0; i < t.NumFields(); i++ {
+			field := t.Field(i)
+			name := field.Name()
+			if field.Embedded() {
+				name = ""
+			}
+			s.Fields = append(s.Fields, StructField{
+				Name: name,
+				Type: c.fromTypes(field.Type()),
+				Tag:  t.Tag(i),
+			})
+		}
+		return s
+	case *types.Signature:
+		return c.fromSignature(t)
+	case *types.Interface:
+		var i Interface
+		for n := 0; n < t.NumEmbeddeds(); n++ {
+			i.Embeds = append(i.Embeds, c.fromTypes(t.EmbeddedType(n)))
+		}
+		for n := 0; n < t.NumExplicitMethods(); n++ {
+			method := t.ExplicitMethod(n)
+			i.Methods = append(i.Methods, InterfaceMethod{
+				Name: method.Name(),
+				Type: c.fromSignature(method.Type().(*types.Signature)),
+			})
+		}
+		return i
+	case *types.Union:
+		var u Union
+		for n := 0; n < t.Len(); n++ {
+			term := t.Term(n)
+			u.Terms = append(u.Terms, Term{Tilde: term.Tilde(), Type: c.fromTypes(term.Type())})
+		}
+		return u
+	}
+	return Ident(typ.String())
+}
+
+func (c *Checked) fromSignature(sig *types.Signature) Func {
+	var f Func
+	for i := 0; i < sig.Params().Len(); i++ {
+		param := sig.Params().At(i)
+		f.Params = append(f.Params, FuncParam{Name: param.Name(), Type: c.fromTypes(param.Type())})
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		result := sig.Results().At(i)
+		f.Results = append(f.Results, FuncResult{Name: result.Name(), Type: c.fromTypes(result.Type())})
+	}
+	f.Variadic = sig.Variadic()
+	return f
+}
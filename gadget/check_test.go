@@ -0,0 +1,211 @@
+package gadget
+
+import (
+	"go/constant"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPackage_Check(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gadget")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	src := `package test
+
+import (
+	"io"
+	"time"
+)
+
+type Timeout time.Duration
+
+type Stream interface {
+	io.ReadCloser
+	Flush() error
+}
+
+type Config struct {
+	Wait Timeout
+	Name string
+}
+
+const Answer = 6 * 7
+
+var Default Config
+
+func (c Config) Generated() Missing { return nil }
+`
+	path := filepath.Join(dir, "test.go")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	p, err := NewPackage(dir, "test")
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+	c, err := p.Check()
+	if err != nil {
+		t.Fatalf("failed to check package: %v", err)
+	}
+	if len(c.Errors) == 0 {
+		t.Fatalf("expected a type error for the undeclared Missing type")
+	}
+
+	u, err := c.Underlying(Ident("Timeout"))
+	if err != nil {
+		t.Fatalf("failed to get underlying type: %v", err)
+	}
+	if u != Int64 {
+		t.Fatalf("expected underlying type int64, got %v", u)
+	}
+
+	a, err := c.Annotate(Position{Path: path}, Selector{Left: "time", Right: "Duration"})
+	if err != nil {
+		t.Fatalf("failed to annotate: %v", err)
+	}
+	if !a.Named || a.Package != "time" || a.Underlying != Int64 {
+		t.Fatalf("unexpected annotation: %#v", a)
+	}
+
+	a, err = c.Annotate(Position{Path: path}, Slice{Elem: Ident("Config")})
+	if err != nil {
+		t.Fatalf("failed to annotate: %v", err)
+	}
+	expected := Slice{Elem: Ident("Config")}
+	if a.Named || !reflect.DeepEqual(a.Underlying, expected) {
+		t.Fatalf("unexpected annotation: %#v", a)
+	}
+
+	methods, err := c.InterfaceMethods(Position{Path: path}, Ident("Stream"))
+	if err != nil {
+		t.Fatalf("failed to get interface methods: %v", err)
+	}
+	var names []string
+	for _, method := range methods {
+		names = append(names, method.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Close", "Flush", "Read"}) {
+		t.Fatalf("unexpected interface methods: %v", names)
+	}
+	if !SameType(methods[2].Type, Func{Params: []FuncParam{{Name: "p", Type: Bytes}}, Results: []FuncResult{{Name: "n", Type: Int}, {Name: "err", Type: Error}}}) {
+		t.Fatalf("unexpected type of Read: %s", methods[2].Type)
+	}
+	if _, err := c.InterfaceMethods(Position{Path: path}, Ident("Config")); err == nil {
+		t.Fatalf("expected error for non-interface type")
+	}
+
+	o, err := c.Lookup("Config")
+	if err != nil {
+		t.Fatalf("failed to look up Config: %v", err)
+	}
+	expectedStruct := Struct{Fields: []StructField{{Name: "Wait", Type: Ident("Timeout")}, {Name: "Name", Type: String}}}
+	if o.Kind != TypeObject || o.Package != "test" || !SameType(o.Underlying, expectedStruct) {
+		t.Fatalf("unexpected object: %#v", o)
+	}
+
+	o, err = c.Lookup("Answer")
+	if err != nil {
+		t.Fatalf("failed to look up Answer: %v", err)
+	}
+	if o.Kind != ConstObject || o.Type != Ident("untyped int") || !constant.Compare(o.Value, token.EQL, constant.MakeInt64(42)) {
+		t.Fatalf("unexpected object: %#v", o)
+	}
+
+	for name, kind := range map[string]ObjectKind{
+		"Default": VarObject,
+		"time":    UnknownObject,
+		"len":     BuiltinObject,
+		"nil":     NilObject,
+		"int":     TypeObject,
+	} {
+		o, err := c.Lookup(name)
+		if kind == UnknownObject {
+			if err == nil {
+				t.Errorf("expected %s not to be found at package scope", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to look up %s: %v", name, err)
+		}
+		if o.Kind != kind {
+			t.Errorf("expected %s to be %s, got %s", name, kind, o.Kind)
+		}
+	}
+
+	if _, err := c.Annotate(Position{Path: path}, Ident("Undeclared")); err == nil {
+		t.Fatalf("expected error for undeclared type")
+	}
+}
+
+func TestPackage_CheckNoTrailingNewline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gadget")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.go")
+	if err := ioutil.WriteFile(path, []byte("package test\n\nimport \"time\"\n\ntype Timeout time.Duration"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	p, err := NewPackage(dir, "test")
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+	c, err := p.Check()
+	if err != nil {
+		t.Fatalf("failed to check package: %v", err)
+	}
+	a, err := c.Annotate(Position{Path: path}, Selector{Left: "time", Right: "Duration"})
+	if err != nil {
+		t.Fatalf("failed to annotate: %v", err)
+	}
+	if !a.Named || a.Package != "time" || a.Underlying != Int64 {
+		t.Fatalf("unexpected annotation: %#v", a)
+	}
+}
+
+func TestPackage_CheckImportPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gadget")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("failed to create package dir: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module \"example.com/mod\" // comment\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatalf("failed to write go.mod: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(sub, "sub.go"), []byte("package sub\n\ntype Config struct{}\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	p, err := NewPackage(sub, "sub")
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+	if p.ImportPath != "example.com/mod/sub" {
+		t.Fatalf("expected import path example.com/mod/sub, got %s", p.ImportPath)
+	}
+	c, err := p.Check()
+	if err != nil {
+		t.Fatalf("failed to check package: %v", err)
+	}
+	if len(c.files) != 1 || c.files[0] != p.syntax[0] {
+		t.Fatalf("expected Check to reuse the parsed files")
+	}
+	o, err := c.Lookup("Config")
+	if err != nil {
+		t.Fatalf("failed to look up Config: %v", err)
+	}
+	if o.Package != "example.com/mod/sub" {
+		t.Fatalf("expected package example.com/mod/sub, got %s", o.Package)
+	}
+}
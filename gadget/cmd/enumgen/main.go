@@ -0,0 +1,36 @@
+// Command enumgen generates String, Parse, Values, IsValid and text and JSON marshaling for an enum type.
+//
+// The go:generate directive precedes either the enum type, or the const block declaring its values:
+//
+//	//go:generate go run github.com/PieterD/pkg/gadget/cmd/enumgen -trimprefix=Color -transform=snake
+//	type Color int
+//
+// The code is written to <file>_enum.go.
+//
+// Outside of go generate, the file and type are passed as flags, followed by the enumgen flags:
+//
+//	go run github.com/PieterD/pkg/gadget/cmd/enumgen -file color.go -type Color -- -trimprefix=Color
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/PieterD/pkg/gadget"
+	"github.com/PieterD/pkg/gadget/enumgen"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "enumgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	info, err := gadget.Load()
+	if err != nil {
+		return fmt.Errorf("failed to fetch generator info: %w", err)
+	}
+	return enumgen.Run(info)
+}
@@ -0,0 +1,36 @@
+// Command fakegen generates a fake implementation of the interface following its go:generate directive.
+//
+// Usage:
+//
+//	//go:generate go run github.com/PieterD/pkg/gadget/cmd/fakegen
+//	type Store interface { ... }
+//
+// The fake is written to <file>_fake.go.
+//
+// Outside of go generate, the file and type are passed as flags, followed by the fakegen flags:
+//
+//	go run github.com/PieterD/pkg/gadget/cmd/fakegen -file store.go -type Store -- -name StubStore
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/PieterD/pkg/gadget"
+	"github.com/PieterD/pkg/gadget/fakegen"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "fakegen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	info, err := gadget.Load()
+	if err != nil {
+		return fmt.Errorf("failed to fetch generator info: %w", err)
+	}
+	return fakegen.Run(info)
+}
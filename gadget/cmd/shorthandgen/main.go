@@ -0,0 +1,36 @@
+// Command shorthandgen generates MarshalShorthand and UnmarshalShorthand methods for the struct type following its go:generate directive.
+//
+// Usage:
+//
+//	//go:generate go run github.com/PieterD/pkg/gadget/cmd/shorthandgen
+//	type Message struct { ... }
+//
+// The methods are written to <file>_shorthand.go.
+//
+// Outside of go generate, the file and type are passed as flags:
+//
+//	go run github.com/PieterD/pkg/gadget/cmd/shorthandgen -file message.go -type Message
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/PieterD/pkg/gadget"
+	"github.com/PieterD/pkg/gadget/shorthandgen"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "shorthandgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	info, err := gadget.Load()
+	if err != nil {
+		return fmt.Errorf("failed to fetch generator info: %w", err)
+	}
+	return shorthandgen.Run(info)
+}
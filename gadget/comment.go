@@ -0,0 +1,89 @@
+package gadget
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// Directive is a comment directive like //go:generate or //gadget:enum.
+// Directives are written without a space between the slashes and the tool name.
+type Directive struct {
+	Tool string // The tool the directive is meant for, like go or gadget.
+	Name string // The name of the directive, like generate or enum.
+	Args string // The arguments following the name, with surrounding space trimmed.
+}
+
+func (d Directive) String() string {
+	if d.Args == "" {
+		return "//" + d.Tool + ":" + d.Name
+	}
+	return "//" + d.Tool + ":" + d.Name + " " + d.Args
+}
+
+// Directives is a list of directives, in the order they appear in the source.
+type Directives []Directive
+
+// Get returns the first directive for the given tool and name.
+func (ds Directives) Get(tool, name string) (Directive, bool) {
+	for _, d := range ds {
+		if d.Tool == tool && d.Name == name {
+			return d, true
+		}
+	}
+	return Directive{}, false
+}
+
+// parseDirective parses a comment like //tool:name args.
+func parseDirective(comment string) (Directive, bool) {
+	if !strings.HasPrefix(comment, "//") {
+		return Directive{}, false
+	}
+	text := comment[2:]
+	colon := strings.IndexByte(text, ':')
+	if colon <= 0 {
+		return Directive{}, false
+	}
+	tool := text[:colon]
+	for _, r := range tool {
+		if !('a' <= r && r <= 'z' || '0' <= r && r <= '9') {
+			return Directive{}, false
+		}
+	}
+	rest := text[colon+1:]
+	if rest == "" || !('a' <= rest[0] && rest[0] <= 'z') {
+		return Directive{}, false
+	}
+	name := rest
+	args := ""
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		name = rest[:i]
+		args = strings.TrimSpace(rest[i:])
+	}
+	return Directive{Tool: tool, Name: name, Args: args}, true
+}
+
+// parseComments returns the text of a comment group without directives, and the directives it contains.
+func parseComments(groups ...*ast.CommentGroup) (string, Directives) {
+	var doc string
+	var directives Directives
+	for i, group := range groups {
+		if group == nil {
+			continue
+		}
+		if i == 0 {
+			doc = group.Text()
+		}
+		for _, comment := range group.List {
+			if d, ok := parseDirective(comment.Text); ok {
+				directives = append(directives, d)
+			}
+		}
+	}
+	return doc, directives
+}
+
+// fieldComments returns the doc text of a struct field or interface method,
+// and the directives in both its doc comment and its line comment.
+func fieldComments(field *ast.Field) (string, Directives) {
+	return parseComments(field.Doc, field.Comment)
+}
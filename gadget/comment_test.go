@@ -0,0 +1,114 @@
+package gadget
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDirective(t *testing.T) {
+	for comment, want := range map[string]*Directive{
+		"//go:generate go run ./":    {Tool: "go", Name: "generate", Args: "go run ./"},
+		"//gadget:enum":              {Tool: "gadget", Name: "enum"},
+		"//gadget:enum  trim=Color ": {Tool: "gadget", Name: "enum", Args: "trim=Color"},
+		"// gadget:enum":             nil,
+		"//Gadget:enum":              nil,
+		"//gadget: enum":             nil,
+		"//http://example.com":       nil,
+		"/* gadget:enum */":          nil,
+	} {
+		d, ok := parseDirective(comment)
+		if want == nil {
+			if ok {
+				t.Errorf("expected %q not to be a directive, got %#v", comment, d)
+			}
+			continue
+		}
+		if !ok || d != *want {
+			t.Errorf("expected %q to be directive %#v, got %#v", comment, *want, d)
+		}
+		if d.String() != strings.Join(strings.Fields(comment), " ") {
+			t.Errorf("expected directive string %q, got %q", comment, d.String())
+		}
+	}
+}
+
+func TestNewFile_Comments(t *testing.T) {
+	src := `package test
+
+// Color is a color.
+//gadget:enum trim=Color
+type Color int
+
+type (
+	// Shape has sides.
+	Shape struct {
+		// Sides is the number of sides.
+		//gadget:min 3
+		Sides int //gadget:max 12
+		Name  string // Not a directive.
+	}
+
+	Plain int
+)
+
+// Painter paints.
+type Painter interface {
+	// Paint applies a color.
+	//gadget:trace
+	Paint(c Color)
+}
+
+// Mix mixes colors.
+//
+//go:noinline
+func Mix(a, b Color) Color { return a }
+`
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	color, _ := f.LookupType("Color")
+	if color.Doc != "Color is a color.\n" {
+		t.Errorf("unexpected doc for Color: %q", color.Doc)
+	}
+	if d, ok := color.Directives.Get("gadget", "enum"); !ok || d.Args != "trim=Color" {
+		t.Errorf("unexpected directives for Color: %#v", color.Directives)
+	}
+
+	shape, _ := f.LookupType("Shape")
+	if shape.Doc != "Shape has sides.\n" {
+		t.Errorf("unexpected doc for Shape: %q", shape.Doc)
+	}
+	sides := shape.Type.(Struct).Fields[0]
+	if sides.Doc != "Sides is the number of sides.\n" {
+		t.Errorf("unexpected doc for Sides: %q", sides.Doc)
+	}
+	expected := Directives{{Tool: "gadget", Name: "min", Args: "3"}, {Tool: "gadget", Name: "max", Args: "12"}}
+	if !reflect.DeepEqual(expected, sides.Directives) {
+		t.Errorf("unexpected directives for Sides: %#v", sides.Directives)
+	}
+	if name := shape.Type.(Struct).Fields[1]; name.Doc != "" || name.Directives != nil {
+		t.Errorf("unexpected comments for Name: %#v", name)
+	}
+
+	plain, _ := f.LookupType("Plain")
+	if plain.Doc != "" || plain.Directives != nil {
+		t.Errorf("unexpected comments for Plain: %#v", plain)
+	}
+
+	painter, _ := f.LookupType("Painter")
+	paint := painter.Type.(Interface).Methods[0]
+	if paint.Doc != "Paint applies a color.\n" || len(paint.Directives) != 1 || paint.Directives[0].Name != "trace" {
+		t.Errorf("unexpected comments for Paint: %#v", paint)
+	}
+
+	mix := f.Funcs[0]
+	if mix.Doc != "Mix mixes colors.\n" {
+		t.Errorf("unexpected doc for Mix: %q", mix.Doc)
+	}
+	if _, ok := mix.Directives.Get("go", "noinline"); !ok {
+		t.Errorf("unexpected directives for Mix: %#v", mix.Directives)
+	}
+}
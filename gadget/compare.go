@@ -0,0 +1,421 @@
+package gadget
+
+import (
+	"sort"
+)
+
+// Decls gives access to the declarations of a File or Package.
+type Decls interface {
+	LookupType(name string) (TypeDecl, bool)
+	MethodSet(typeName string, pointer bool) (*MethodSet, error)
+	MissingMethods(typeName string, pointer bool, iface Interface) ([]MethodMismatch, error)
+	InterfaceMethods(iface Interface) *MethodSet
+}
+
+// Comparer compares types semantically.
+// The zero Comparer checks whether types are written identically, up to the order of interface methods, embedded interface elements and union terms.
+type Comparer struct {
+	IgnoreParamNames bool  // Ignore the names of function parameters and results.
+	IgnoreTags       bool  // Ignore struct field tags.
+	ResolveAliases   bool  // Follow alias declarations found in Decls, like type A = B.
+	ByteRuneAliases  bool  // Treat byte and uint8, and rune and int32, as identical.
+	Decls            Decls // The declarations used to resolve aliases, underlying types and method sets. May be nil.
+}
+
+// Identical returns true if the types are identical, according to the options of the Comparer.
+func (c Comparer) Identical(t, t2 Type) bool {
+	t = c.normalize(t)
+	t2 = c.normalize(t2)
+	if t == nil || t2 == nil {
+		return t == nil && t2 == nil
+	}
+	switch t := t.(type) {
+	case Ident, Selector, TypeParam:
+		return t == t2
+	case Pointer:
+		t2, ok := t2.(Pointer)
+		return ok && c.Identical(t.Elem, t2.Elem)
+	case Slice:
+		t2, ok := t2.(Slice)
+		return ok && c.Identical(t.Elem, t2.Elem)
+	case Array:
+		t2, ok := t2.(Array)
+		return ok && t.Size == t2.Size && t.Len == t2.Len && c.Identical(t.Elem, t2.Elem)
+	case Map:
+		t2, ok := t2.(Map)
+		return ok && c.Identical(t.Key, t2.Key) && c.Identical(t.Value, t2.Value)
+	case Chan:
+		t2, ok := t2.(Chan)
+		return ok && t.Dir == t2.Dir && c.Identical(t.Elem, t2.Elem)
+	case Struct:
+		t2, ok := t2.(Struct)
+		if !ok || len(t.Fields) != len(t2.Fields) {
+			return false
+		}
+		for i, field := range t.Fields {
+			field2 := t2.Fields[i]
+			if field.Name != field2.Name || !c.Identical(field.Type, field2.Type) {
+				return false
+			}
+			if !c.IgnoreTags && field.Tag != field2.Tag {
+				return false
+			}
+		}
+		return true
+	case Func:
+		t2, ok := t2.(Func)
+		return ok && c.identicalFunc(t, t2)
+	case Interface:
+		t2, ok := t2.(Interface)
+		if !ok || len(t.Methods) != len(t2.Methods) || len(t.Embeds) != len(t2.Embeds) {
+			return false
+		}
+		methods := sortedMethods(t.Methods)
+		methods2 := sortedMethods(t2.Methods)
+		for i, method := range methods {
+			if method.Name != methods2[i].Name || !c.identicalFunc(method.Type, methods2[i].Type) {
+				return false
+			}
+		}
+		used := make([]bool, len(t2.Embeds))
+	embeds:
+		for _, embed := range t.Embeds {
+			for i, embed2 := range t2.Embeds {
+				if !used[i] && c.Identical(embed, embed2) {
+					used[i] = true
+					continue embeds
+				}
+			}
+			return false
+		}
+		return true
+	case Instance:
+		t2, ok := t2.(Instance)
+		if !ok || len(t.Args) != len(t2.Args) || !c.Identical(t.Type, t2.Type) {
+			return false
+		}
+		for i, arg := range t.Args {
+			if !c.Identical(arg, t2.Args[i]) {
+				return false
+			}
+		}
+		return true
+	case Union:
+		t2, ok := t2.(Union)
+		if !ok || len(t.Terms) != len(t2.Terms) {
+			return false
+		}
+		used := make([]bool, len(t2.Terms))
+	terms:
+		for _, term := range t.Terms {
+			for i, term2 := range t2.Terms {
+				if !used[i] && term.Tilde == term2.Tilde && c.Identical(term.Type, term2.Type) {
+					used[i] = true
+					continue terms
+				}
+			}
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func (c Comparer) identicalFunc(f, f2 Func) bool {
+	if len(f.Params) != len(f2.Params) || len(f.Results) != len(f2.Results) || f.Variadic != f2.Variadic {
+		return false
+	}
+	for i, param := range f.Params {
+		if !c.IgnoreParamNames && param.Name != f2.Params[i].Name {
+			return false
+		}
+		if !c.Identical(param.Type, f2.Params[i].Type) {
+			return false
+		}
+	}
+	for i, result := range f.Results {
+		if !c.IgnoreParamNames && result.Name != f2.Results[i].Name {
+			return false
+		}
+		if !c.Identical(result.Type, f2.Results[i].Type) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedMethods(methods []InterfaceMethod) []InterfaceMethod {
+	sorted := append([]InterfaceMethod(nil), methods...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// normalize applies the alias options of the Comparer to the outermost type.
+func (c Comparer) normalize(t Type) Type {
+	if c.ResolveAliases && c.Decls != nil {
+		for i := 0; i < 100; i++ {
+			id, ok := t.(Ident)
+			if !ok {
+				break
+			}
+			decl, ok := c.Decls.LookupType(string(id))
+			if !ok || decl.Alias == nil {
+				break
+			}
+			t = decl.Alias
+		}
+	}
+	if c.ByteRuneAliases {
+		switch t {
+		case Byte:
+			return Uint8
+		case Rune:
+			return Int32
+		}
+	}
+	return t
+}
+
+// Underlying returns the underlying type of t, following type definitions and aliases found in Decls.
+// The underlying type of a builtin type is itself.
+// If the underlying type can not be determined, like for a Selector, Underlying returns false.
+func (c Comparer) Underlying(t Type) (Type, bool) {
+	for i := 0; i < 100; i++ {
+		id, ok := t.(Ident)
+		if !ok {
+			break
+		}
+		if builtinTypes[string(id)] {
+			switch id {
+			case Error:
+				return errorInterface, true
+			case Ident("any"):
+				return Interface{}, true
+			}
+			return id, true
+		}
+		if c.Decls == nil {
+			return t, false
+		}
+		decl, ok := c.Decls.LookupType(string(id))
+		if !ok {
+			return t, false
+		}
+		if decl.Alias != nil {
+			t = decl.Alias
+			continue
+		}
+		t = decl.Type
+	}
+	switch t.(type) {
+	case Ident:
+		// Still a declared type after following 100 declarations, so the declarations form a cycle.
+		return t, false
+	case Selector, TypeParam, Instance:
+		return t, false
+	}
+	return t, true
+}
+
+var errorInterface = Interface{Methods: []InterfaceMethod{{Name: "Error", Type: Func{Results: []FuncResult{{Type: String}}}}}}
+
+// isNamed returns true for named types: builtins, declared types, type parameters and instantiated generics.
+func isNamed(t Type) bool {
+	switch t.(type) {
+	case Ident, Selector, TypeParam, Instance:
+		return true
+	}
+	return false
+}
+
+// AssignableTo returns true if a value of type v is assignable to a variable of type t,
+// following the rules of the Go spec as far as they can be checked using Decls.
+// The identifier nil is treated as the type of the untyped nil value.
+func (c Comparer) AssignableTo(v, t Type) bool {
+	if c.Identical(v, t) {
+		return true
+	}
+	tu, tok := c.Underlying(t)
+	if !tok {
+		return false
+	}
+	if v == Ident("nil") {
+		switch tu.(type) {
+		case Pointer, Func, Slice, Map, Chan, Interface:
+			return true
+		}
+		return false
+	}
+	vu, vok := c.Underlying(v)
+	if vok && (!isNamed(v) || !isNamed(t)) && c.Identical(vu, tu) {
+		return true
+	}
+	if iface, ok := tu.(Interface); ok {
+		return c.implements(v, iface)
+	}
+	if vok && (!isNamed(v) || !isNamed(t)) {
+		vc, vIsChan := vu.(Chan)
+		tc, tIsChan := tu.(Chan)
+		if vIsChan && tIsChan && vc.Dir == BOTH && c.Identical(vc.Elem, tc.Elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// implements checks whether v satisfies iface.
+func (c Comparer) implements(v Type, iface Interface) bool {
+	if len(iface.Methods) == 0 && len(iface.Embeds) == 0 {
+		return true
+	}
+	if vu, ok := c.Underlying(v); ok {
+		if viface, ok := vu.(Interface); ok {
+			want := iface.Methods
+			have := viface.Methods
+			if c.Decls != nil {
+				want = flatMethods(c.Decls, iface)
+				have = flatMethods(c.Decls, viface)
+			}
+			for _, method := range want {
+				found := false
+				for _, method2 := range have {
+					if method.Name == method2.Name && c.identicalFunc(method.Type, method2.Type) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	if c.Decls == nil {
+		return false
+	}
+	pointer := false
+	if ptr, ok := v.(Pointer); ok {
+		v = ptr.Elem
+		pointer = true
+	}
+	v = c.normalize(v)
+	id, ok := v.(Ident)
+	if !ok {
+		return false
+	}
+	missing, err := c.Decls.MissingMethods(string(id), pointer, iface)
+	return err == nil && len(missing) == 0
+}
+
+// flatMethods returns the methods of iface, including those of embedded interfaces.
+func flatMethods(decls Decls, iface Interface) []InterfaceMethod {
+	var methods []InterfaceMethod
+	for _, method := range decls.InterfaceMethods(iface).Methods {
+		methods = append(methods, InterfaceMethod{Name: method.Name, Type: method.Type})
+	}
+	return methods
+}
+
+// ConvertibleTo returns true if a value of type v can be converted to type t,
+// following the rules of the Go spec as far as they can be checked using Decls.
+func (c Comparer) ConvertibleTo(v, t Type) bool {
+	if c.AssignableTo(v, t) {
+		return true
+	}
+	vu, vok := c.Underlying(v)
+	tu, tok := c.Underlying(t)
+	if !vok || !tok {
+		return false
+	}
+	noTags := c
+	noTags.IgnoreTags = true
+	if noTags.Identical(vu, tu) {
+		return true
+	}
+	if vp, ok := v.(Pointer); ok {
+		if tp, ok := t.(Pointer); ok {
+			vpu, vok := c.Underlying(vp.Elem)
+			tpu, tok := c.Underlying(tp.Elem)
+			if vok && tok && noTags.Identical(vpu, tpu) {
+				return true
+			}
+		}
+	}
+	vk := basicKind(vu)
+	tk := basicKind(tu)
+	if (vk == integerKind || vk == floatKind) && (tk == integerKind || tk == floatKind) {
+		return true
+	}
+	if vk == complexKind && tk == complexKind {
+		return true
+	}
+	if tk == stringKind && (vk == integerKind || c.isByteOrRuneSlice(vu)) {
+		return true
+	}
+	if vk == stringKind && c.isByteOrRuneSlice(tu) {
+		return true
+	}
+	if vs, ok := vu.(Slice); ok {
+		switch tu := tu.(type) {
+		case Array:
+			return c.Identical(vs.Elem, tu.Elem)
+		case Pointer:
+			if array, ok := c.underlyingArray(tu.Elem); ok {
+				return c.Identical(vs.Elem, array.Elem)
+			}
+		}
+	}
+	return false
+}
+
+func (c Comparer) underlyingArray(t Type) (Array, bool) {
+	u, ok := c.Underlying(t)
+	if !ok {
+		return Array{}, false
+	}
+	array, ok := u.(Array)
+	return array, ok
+}
+
+func (c Comparer) isByteOrRuneSlice(t Type) bool {
+	s, ok := t.(Slice)
+	if !ok {
+		return false
+	}
+	elem, ok := c.Underlying(s.Elem)
+	if !ok {
+		return false
+	}
+	switch elem {
+	case Byte, Uint8, Rune, Int32:
+		return true
+	}
+	return false
+}
+
+type kind int
+
+const (
+	otherKind kind = iota
+	integerKind
+	floatKind
+	complexKind
+	stringKind
+)
+
+func basicKind(t Type) kind {
+	switch t {
+	case Int, Int8, Int16, Int32, Int64, Uint, Uint8, Uint16, Uint32, Uint64, Uintptr, Byte, Rune:
+		return integerKind
+	case Float32, Float64:
+		return floatKind
+	case Complex64, Complex128:
+		return complexKind
+	case String:
+		return stringKind
+	}
+	return otherKind
+}
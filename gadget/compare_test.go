@@ -0,0 +1,139 @@
+package gadget
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComparer_Identical(t *testing.T) {
+	f, err := NewFile("test.go", strings.NewReader(`package test
+
+type Bytes = []byte
+type Chain = Bytes
+`))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	for _, test := range []struct {
+		c     Comparer
+		a, b  string
+		equal bool
+	}{
+		{c: Comparer{}, a: "func(a int)", b: "func(a int)", equal: true},
+		{c: Comparer{}, a: "func(a int)", b: "func(b int)", equal: false},
+		{c: Comparer{IgnoreParamNames: true}, a: "func(a int) (err error)", b: "func(b int) error", equal: true},
+		{c: Comparer{IgnoreParamNames: true}, a: "func(a ...int)", b: "func(a []int)", equal: false},
+		{c: Comparer{}, a: "struct{A int `json:\"a\"`}", b: "struct{A int}", equal: false},
+		{c: Comparer{IgnoreTags: true}, a: "struct{A int `json:\"a\"`}", b: "struct{A int}", equal: true},
+		{c: Comparer{IgnoreTags: true}, a: "struct{A int}", b: "struct{B int}", equal: false},
+		{c: Comparer{}, a: "[]byte", b: "[]uint8", equal: false},
+		{c: Comparer{ByteRuneAliases: true}, a: "map[rune][]byte", b: "map[int32][]uint8", equal: true},
+		{c: Comparer{}, a: "Chain", b: "[]byte", equal: false},
+		{c: Comparer{ResolveAliases: true, Decls: f}, a: "Chain", b: "[]byte", equal: true},
+		{c: Comparer{ResolveAliases: true, Decls: f}, a: "map[string]Chain", b: "map[string]Bytes", equal: true},
+		{c: Comparer{}, a: "interface{A(); B()}", b: "interface{B(); A()}", equal: true},
+		{c: Comparer{}, a: "interface{~int | string}", b: "interface{string | ~int}", equal: true},
+		{c: Comparer{}, a: "interface{~int | string}", b: "interface{string | int}", equal: false},
+		{c: Comparer{}, a: "interface{io.Reader; io.Closer}", b: "interface{io.Closer; io.Reader}", equal: true},
+		{c: Comparer{}, a: "interface{io.Reader; io.Reader}", b: "interface{io.Reader; io.Closer}", equal: false},
+		{c: Comparer{}, a: "List[int]", b: "List[int]", equal: true},
+		{c: Comparer{}, a: "chan<- int", b: "chan int", equal: false},
+	} {
+		a, err := ParseType(test.a)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", test.a, err)
+		}
+		b, err := ParseType(test.b)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", test.b, err)
+		}
+		if got := test.c.Identical(a, b); got != test.equal {
+			t.Errorf("%+v: expected Identical(%s, %s) to be %t", test.c, test.a, test.b, test.equal)
+		}
+	}
+}
+
+func TestComparer_AssignableConvertible(t *testing.T) {
+	f, err := NewFile("test.go", strings.NewReader(`package test
+
+type Celsius float64
+type Names []string
+type Point struct { X, Y int }
+type TaggedPoint struct { X, Y int `+"`json:\"xy\"`"+` }
+type Stringer interface { String() string }
+type Named string
+func (n Named) String() string { return string(n) }
+type Ptr struct{}
+func (p *Ptr) String() string { return "" }
+`))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	c := Comparer{Decls: f}
+	for _, test := range []struct {
+		v, t        string
+		assignable  bool
+		convertible bool
+	}{
+		{v: "Celsius", t: "Celsius", assignable: true, convertible: true},
+		{v: "Celsius", t: "float64", assignable: false, convertible: true},
+		{v: "Celsius", t: "int", assignable: false, convertible: true},
+		{v: "[]string", t: "Names", assignable: true, convertible: true},
+		{v: "Names", t: "[]int", assignable: false, convertible: false},
+		{v: "nil", t: "Names", assignable: true, convertible: true},
+		{v: "nil", t: "Celsius", assignable: false, convertible: false},
+		{v: "Named", t: "Stringer", assignable: true, convertible: true},
+		{v: "Ptr", t: "Stringer", assignable: false, convertible: false},
+		{v: "*Ptr", t: "Stringer", assignable: true, convertible: true},
+		{v: "int", t: "interface{}", assignable: true, convertible: true},
+		{v: "Stringer", t: "interface{String() string}", assignable: true, convertible: true},
+		{v: "chan int", t: "<-chan int", assignable: true, convertible: true},
+		{v: "Point", t: "TaggedPoint", assignable: false, convertible: true},
+		{v: "*Point", t: "*TaggedPoint", assignable: false, convertible: true},
+		{v: "int", t: "string", assignable: false, convertible: true},
+		{v: "string", t: "[]rune", assignable: false, convertible: true},
+		{v: "[]byte", t: "Named", assignable: false, convertible: true},
+		{v: "[]int", t: "[4]int", assignable: false, convertible: true},
+		{v: "[]int", t: "*[4]int", assignable: false, convertible: true},
+		{v: "string", t: "bool", assignable: false, convertible: false},
+		{v: "io.Reader", t: "io.Reader", assignable: true, convertible: true},
+		{v: "io.Reader", t: "io.Writer", assignable: false, convertible: false},
+	} {
+		v, err := ParseType(test.v)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", test.v, err)
+		}
+		typ, err := ParseType(test.t)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", test.t, err)
+		}
+		if got := c.AssignableTo(v, typ); got != test.assignable {
+			t.Errorf("expected AssignableTo(%s, %s) to be %t", test.v, test.t, test.assignable)
+		}
+		if got := c.ConvertibleTo(v, typ); got != test.convertible {
+			t.Errorf("expected ConvertibleTo(%s, %s) to be %t", test.v, test.t, test.convertible)
+		}
+	}
+}
+
+func TestComparer_Underlying(t *testing.T) {
+	f, err := NewFile("test.go", strings.NewReader(`package test
+
+type Celsius float64
+type A B
+type B A
+type Self = Self
+`))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	c := Comparer{Decls: f}
+	if u, ok := c.Underlying(Ident("Celsius")); !ok || u != Float64 {
+		t.Fatalf("expected float64, got %v, %t", u, ok)
+	}
+	for _, name := range []string{"A", "Self", "Missing"} {
+		if u, ok := c.Underlying(Ident(name)); ok {
+			t.Errorf("expected no underlying type for %s, got %v", name, u)
+		}
+	}
+}
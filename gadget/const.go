@@ -0,0 +1,270 @@
+package gadget
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"math"
+)
+
+type ConstDecl struct {
+	Position
+	Name  string         // The constant name.
+	Type  Type           // The declared type, or the type repeated from a previous spec in the block. Nil if untyped.
+	Value constant.Value // The evaluated value. Its Kind is constant.Unknown if it could not be evaluated, or does not fit its type.
+	Iota  int            // The value of iota for this constant.
+	Group int            // The constants declared in the same const block share a Group, numbered from 0 within the file.
+}
+
+type VarDecl struct {
+	Position
+	Name string // The variable name.
+	Type Type   // The declared type. Nil if the type is inferred from the value.
+}
+
+// ArrayLen returns the length of an array type, evaluating its length expression using the constants declared in the file.
+func (f *File) ArrayLen(a Array) (int, error) {
+	return arrayLen(f.Consts, a)
+}
+
+// ArrayLen returns the length of an array type, evaluating its length expression using the constants declared in the package.
+func (p *Package) ArrayLen(a Array) (int, error) {
+	return arrayLen(p.Consts, a)
+}
+
+func arrayLen(consts []ConstDecl, a Array) (int, error) {
+	if a.Len == "" {
+		return a.Size, nil
+	}
+	if a.Len == "..." {
+		return 0, fmt.Errorf("length of array [...]%s depends on its composite literal", a.Elem)
+	}
+	expr, err := parser.ParseExpr(a.Len)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse array length '%s': %w", a.Len, err)
+	}
+	e := newConstEvaluator()
+	for _, c := range consts {
+		if c.Value != nil && c.Value.Kind() != constant.Unknown {
+			e.values[c.Name] = c.Value
+		}
+	}
+	v := constant.ToInt(e.eval(expr, 0))
+	size, ok := constant.Int64Val(v)
+	if !ok || v.Kind() != constant.Int || size < 0 {
+		return 0, fmt.Errorf("failed to evaluate array length '%s'", a.Len)
+	}
+	return int(size), nil
+}
+
+// constSpec is a constant waiting to be evaluated.
+type constSpec struct {
+	expr ast.Expr
+	typ  Type
+	iota int
+}
+
+// constEvaluator evaluates constant expressions within a single file.
+// Constants may refer to other constants in the file, in any order.
+type constEvaluator struct {
+	specs    map[string]constSpec
+	values   map[string]constant.Value
+	visiting map[string]bool
+}
+
+func newConstEvaluator() *constEvaluator {
+	return &constEvaluator{
+		specs:    make(map[string]constSpec),
+		values:   make(map[string]constant.Value),
+		visiting: make(map[string]bool),
+	}
+}
+
+func (e *constEvaluator) add(name string, spec constSpec) {
+	if name == "_" {
+		return
+	}
+	e.specs[name] = spec
+}
+
+func (e *constEvaluator) value(name string) constant.Value {
+	if v, ok := e.values[name]; ok {
+		return v
+	}
+	spec, ok := e.specs[name]
+	if !ok || e.visiting[name] {
+		return constant.MakeUnknown()
+	}
+	e.visiting[name] = true
+	v := e.eval(spec.expr, spec.iota)
+	if id, ok := spec.typ.(Ident); ok {
+		v = convertConst(v, id)
+	}
+	e.visiting[name] = false
+	e.values[name] = v
+	return v
+}
+
+func (e *constEvaluator) eval(expr ast.Expr, iota int) constant.Value {
+	unknown := constant.MakeUnknown()
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		return constant.MakeFromLiteral(expr.Value, expr.Kind, 0)
+	case *ast.Ident:
+		switch expr.Name {
+		case "iota":
+			return constant.MakeInt64(int64(iota))
+		case "true":
+			return constant.MakeBool(true)
+		case "false":
+			return constant.MakeBool(false)
+		}
+		return e.value(expr.Name)
+	case *ast.ParenExpr:
+		return e.eval(expr.X, iota)
+	case *ast.UnaryExpr:
+		x := e.eval(expr.X, iota)
+		if x.Kind() == constant.Unknown {
+			return unknown
+		}
+		switch {
+		case expr.Op == token.NOT && x.Kind() == constant.Bool,
+			(expr.Op == token.ADD || expr.Op == token.SUB) && constClass(x) == constant.Int,
+			expr.Op == token.XOR && x.Kind() == constant.Int:
+			return constant.UnaryOp(expr.Op, x, 0)
+		}
+		return unknown
+	case *ast.BinaryExpr:
+		x := e.eval(expr.X, iota)
+		y := e.eval(expr.Y, iota)
+		if x.Kind() == constant.Unknown || y.Kind() == constant.Unknown {
+			return unknown
+		}
+		if expr.Op != token.SHL && expr.Op != token.SHR && constClass(x) != constClass(y) {
+			return unknown
+		}
+		switch expr.Op {
+		case token.SHL, token.SHR:
+			s, ok := constant.Uint64Val(constant.ToInt(y))
+			if !ok || constant.ToInt(x).Kind() != constant.Int {
+				return unknown
+			}
+			return constant.Shift(constant.ToInt(x), expr.Op, uint(s))
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return compareConst(x, expr.Op, y)
+		case token.QUO, token.REM:
+			if y.Kind() != constant.String && y.Kind() != constant.Bool && constant.Sign(y) == 0 {
+				return unknown
+			}
+			if expr.Op == token.QUO && x.Kind() == constant.Int && y.Kind() == constant.Int {
+				return binaryOpConst(x, token.QUO_ASSIGN, y)
+			}
+		}
+		return binaryOpConst(x, expr.Op, y)
+	case *ast.CallExpr:
+		if len(expr.Args) != 1 {
+			return unknown
+		}
+		fun, ok := expr.Fun.(*ast.Ident)
+		if !ok {
+			return unknown
+		}
+		x := e.eval(expr.Args[0], iota)
+		if fun.Name == "len" {
+			if x.Kind() != constant.String {
+				return unknown
+			}
+			return constant.MakeInt64(int64(len(constant.StringVal(x))))
+		}
+		return convertConst(x, Ident(fun.Name))
+	}
+	return unknown
+}
+
+// binaryOpConst is constant.BinaryOp, returning an unknown value instead of panicking on invalid operations.
+func binaryOpConst(x constant.Value, op token.Token, y constant.Value) (v constant.Value) {
+	defer func() {
+		if recover() != nil {
+			v = constant.MakeUnknown()
+		}
+	}()
+	return constant.BinaryOp(x, op, y)
+}
+
+// compareConst is constant.Compare, returning an unknown value instead of panicking on invalid comparisons.
+func compareConst(x constant.Value, op token.Token, y constant.Value) (v constant.Value) {
+	defer func() {
+		if recover() != nil {
+			v = constant.MakeUnknown()
+		}
+	}()
+	return constant.MakeBool(constant.Compare(x, op, y))
+}
+
+// constClass groups constant kinds that may be combined in a binary expression.
+func constClass(v constant.Value) constant.Kind {
+	switch v.Kind() {
+	case constant.Int, constant.Float, constant.Complex:
+		return constant.Int
+	}
+	return v.Kind()
+}
+
+// intBounds are the ranges of the builtin integer types.
+// int, uint and uintptr are given their 64 bit range, as the target architecture is not known.
+var intBounds = map[Ident][2]constant.Value{
+	Int:     {constant.MakeInt64(math.MinInt64), constant.MakeInt64(math.MaxInt64)},
+	Int8:    {constant.MakeInt64(math.MinInt8), constant.MakeInt64(math.MaxInt8)},
+	Int16:   {constant.MakeInt64(math.MinInt16), constant.MakeInt64(math.MaxInt16)},
+	Int32:   {constant.MakeInt64(math.MinInt32), constant.MakeInt64(math.MaxInt32)},
+	Rune:    {constant.MakeInt64(math.MinInt32), constant.MakeInt64(math.MaxInt32)},
+	Int64:   {constant.MakeInt64(math.MinInt64), constant.MakeInt64(math.MaxInt64)},
+	Uint:    {constant.MakeInt64(0), constant.MakeUint64(math.MaxUint64)},
+	Uint8:   {constant.MakeInt64(0), constant.MakeUint64(math.MaxUint8)},
+	Byte:    {constant.MakeInt64(0), constant.MakeUint64(math.MaxUint8)},
+	Uint16:  {constant.MakeInt64(0), constant.MakeUint64(math.MaxUint16)},
+	Uint32:  {constant.MakeInt64(0), constant.MakeUint64(math.MaxUint32)},
+	Uint64:  {constant.MakeInt64(0), constant.MakeUint64(math.MaxUint64)},
+	Uintptr: {constant.MakeInt64(0), constant.MakeUint64(math.MaxUint64)},
+}
+
+// convertConst converts a constant value to the given type, if it is a builtin type.
+// Values that are not representable by the type, like 256 as a uint8, become unknown.
+// Values of other types are returned unchanged.
+func convertConst(v constant.Value, typ Ident) constant.Value {
+	unknown := constant.MakeUnknown()
+	switch typ {
+	case Int, Int8, Int16, Int32, Int64, Uint, Uint8, Uint16, Uint32, Uint64, Uintptr, Byte, Rune:
+		v = constant.ToInt(v)
+		if v.Kind() != constant.Int {
+			return unknown
+		}
+		bounds := intBounds[typ]
+		if constant.Compare(v, token.LSS, bounds[0]) || constant.Compare(v, token.GTR, bounds[1]) {
+			return unknown
+		}
+		return v
+	case Float32, Float64:
+		v = constant.ToFloat(v)
+		if v.Kind() != constant.Float {
+			return unknown
+		}
+		if f, _ := constant.Float64Val(v); math.IsInf(f, 0) || typ == Float32 && math.Abs(f) > math.MaxFloat32 {
+			return unknown
+		}
+		return v
+	case Complex64, Complex128:
+		return constant.ToComplex(v)
+	case String:
+		if v.Kind() == constant.Int {
+			r, ok := constant.Int64Val(v)
+			if !ok {
+				return constant.MakeUnknown()
+			}
+			return constant.MakeString(string(rune(r)))
+		}
+	}
+	return v
+}
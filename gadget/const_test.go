@@ -0,0 +1,153 @@
+package gadget
+
+import (
+	"go/constant"
+	"strings"
+	"testing"
+)
+
+func TestNewFile_Consts(t *testing.T) {
+	src := `package test
+
+const (
+	KB = 1 << (10 * (iota + 1))
+	MB
+	GB
+)
+
+const (
+	_ Weekday = iota
+	Monday
+	Tuesday
+)
+
+const (
+	Name       = "gadget"
+	Greeting   = "hello, " + Name
+	NameLen    = len(Name)
+	Half       = 1.0 / 2
+	IntDiv     = 7 / 2
+	Later      = Earlier * 2
+	Earlier    = 21
+	Neg        = -Earlier
+	Truth      = Earlier > 20 && !false
+	Typed  int = 'a'
+	Letter     = string(65)
+	Unknown    = math.MaxInt8
+	Bad        = 1 / 0
+	Mixed      = "a" + 1
+)
+
+type Weekday int
+
+var (
+	x, y int
+	z    = 3
+)
+
+const (
+	MaxByte   uint8   = 255
+	Overflow  uint8   = 255 + 1
+	Negative  uint    = -1
+	Converted         = int8(128)
+	Fraction  int     = 1.5
+	Huge      float32 = 1e39
+)
+`
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	consts := make(map[string]ConstDecl)
+	for _, c := range f.Consts {
+		consts[c.Name] = c
+	}
+	for name, want := range map[string]constant.Value{
+		"KB":        constant.MakeInt64(1 << 10),
+		"MB":        constant.MakeInt64(1 << 20),
+		"GB":        constant.MakeInt64(1 << 30),
+		"Monday":    constant.MakeInt64(1),
+		"Tuesday":   constant.MakeInt64(2),
+		"Name":      constant.MakeString("gadget"),
+		"Greeting":  constant.MakeString("hello, gadget"),
+		"NameLen":   constant.MakeInt64(6),
+		"Half":      constant.MakeFloat64(0.5),
+		"IntDiv":    constant.MakeInt64(3),
+		"Later":     constant.MakeInt64(42),
+		"Neg":       constant.MakeInt64(-21),
+		"Truth":     constant.MakeBool(true),
+		"Typed":     constant.MakeInt64('a'),
+		"Letter":    constant.MakeString("A"),
+		"Unknown":   constant.MakeUnknown(),
+		"Bad":       constant.MakeUnknown(),
+		"Mixed":     constant.MakeUnknown(),
+		"MaxByte":   constant.MakeInt64(255),
+		"Overflow":  constant.MakeUnknown(),
+		"Negative":  constant.MakeUnknown(),
+		"Converted": constant.MakeUnknown(),
+		"Fraction":  constant.MakeUnknown(),
+		"Huge":      constant.MakeUnknown(),
+	} {
+		c, ok := consts[name]
+		if !ok {
+			t.Errorf("missing const %s", name)
+			continue
+		}
+		if c.Value.Kind() != want.Kind() || c.Value.ExactString() != want.ExactString() {
+			t.Errorf("const %s: want %s, got %s", name, want.ExactString(), c.Value.ExactString())
+		}
+	}
+	if typ := consts["Tuesday"].Type; typ != Ident("Weekday") {
+		t.Errorf("expected repeated type Weekday for Tuesday, got %v", typ)
+	}
+	if iota := consts["Tuesday"].Iota; iota != 2 {
+		t.Errorf("expected iota 2 for Tuesday, got %d", iota)
+	}
+	if typ := consts["KB"].Type; typ != nil {
+		t.Errorf("expected untyped KB, got %v", typ)
+	}
+
+	expectedVars := []VarDecl{
+		{Position: Position{Path: "test.go", Line: 35, Column: 2, EndLine: 35, EndColumn: 10}, Name: "x", Type: Int},
+		{Position: Position{Path: "test.go", Line: 35, Column: 2, EndLine: 35, EndColumn: 10}, Name: "y", Type: Int},
+		{Position: Position{Path: "test.go", Line: 36, Column: 2, EndLine: 36, EndColumn: 10}, Name: "z"},
+	}
+	if len(f.Vars) != len(expectedVars) {
+		t.Fatalf("expected %d vars, got %#v", len(expectedVars), f.Vars)
+	}
+	for i, want := range expectedVars {
+		if f.Vars[i] != want {
+			t.Errorf("var %d: want %#v, got %#v", i, want, f.Vars[i])
+		}
+	}
+}
+
+func TestFile_ArrayLen(t *testing.T) {
+	src := `package test
+
+const Size = 4
+
+type Block [Size * 2]byte
+`
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	a, ok := f.GetTypes()["Block"].(Array)
+	if !ok {
+		t.Fatalf("expected Block to be an array, got %#v", f.GetTypes()["Block"])
+	}
+	if a.Size != -1 || a.Len != "Size * 2" {
+		t.Fatalf("unexpected symbolic array: %#v", a)
+	}
+	size, err := f.ArrayLen(a)
+	if err != nil {
+		t.Fatalf("failed to evaluate array length: %v", err)
+	}
+	if size != 8 {
+		t.Fatalf("expected array length 8, got %d", size)
+	}
+	if _, err := f.ArrayLen(Array{Size: -1, Len: "Missing", Elem: Byte}); err == nil {
+		t.Fatalf("expected error for unknown constant")
+	}
+}
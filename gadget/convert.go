@@ -3,14 +3,95 @@ package gadget
 import (
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
+	"go/types"
 	"strconv"
 )
 
+// converter converts type expressions to Types.
+type converter struct {
+	typeParams map[string]bool // The names of the type parameters in scope.
+	fileSet    *token.FileSet  // The file set used to determine the positions of struct fields. May be nil.
+	path       string          // The path of the file being converted.
+}
+
 func convertTypeSpec(spec ast.Expr) (Type, error) {
+	return converter{}.convertTypeSpec(spec)
+}
+
+// withTypeParams returns a converter with the given type parameters in scope,
+// along with the converted type parameter list.
+func (c converter) withTypeParams(list *ast.FieldList) (converter, []TypeParamDecl, error) {
+	if list == nil || len(list.List) == 0 {
+		return c, nil, nil
+	}
+	inner := converter{
+		typeParams: make(map[string]bool),
+		fileSet:    c.fileSet,
+		path:       c.path,
+	}
+	for name := range c.typeParams {
+		inner.typeParams[name] = true
+	}
+	for _, field := range list.List {
+		for _, name := range field.Names {
+			inner.typeParams[name.Name] = true
+		}
+	}
+	var params []TypeParamDecl
+	for _, field := range list.List {
+		var constraint Type
+		if field.Type != nil {
+			var err error
+			constraint, err = inner.convertTypeSpec(field.Type)
+			if err != nil {
+				return c, nil, fmt.Errorf("failed to convert type parameter constraint: %w", err)
+			}
+		}
+		for _, name := range field.Names {
+			params = append(params, TypeParamDecl{
+				Name:       name.Name,
+				Constraint: constraint,
+			})
+		}
+	}
+	return inner, params, nil
+}
+
+func (c converter) convertTypeSpec(spec ast.Expr) (Type, error) {
 	switch t := spec.(type) {
 	case *ast.Ident:
+		if c.typeParams[t.Name] {
+			return TypeParam(t.Name), nil
+		}
 		return Ident(t.Name), nil
+	case *ast.IndexExpr:
+		return c.convertInstance(t.X, []ast.Expr{t.Index})
+	case *ast.IndexListExpr:
+		return c.convertInstance(t.X, t.Indices)
+	case *ast.BinaryExpr:
+		if t.Op != token.OR {
+			return nil, fmt.Errorf("unexpected binary operator %s in type", t.Op)
+		}
+		left, err := c.convertTypeSpec(t.X)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert union term: %w", err)
+		}
+		right, err := c.convertTypeSpec(t.Y)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert union term: %w", err)
+		}
+		return Union{Terms: append(unionTerms(left), unionTerms(right)...)}, nil
+	case *ast.UnaryExpr:
+		if t.Op != token.TILDE {
+			return nil, fmt.Errorf("unexpected unary operator %s in type", t.Op)
+		}
+		elem, err := c.convertTypeSpec(t.X)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert approximation term: %w", err)
+		}
+		return Union{Terms: []Term{{Tilde: true, Type: elem}}}, nil
 	case *ast.SelectorExpr:
 		ident, ok := t.X.(*ast.Ident)
 		if !ok {
@@ -21,7 +102,7 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 			Right: Ident(t.Sel.Name),
 		}, nil
 	case *ast.StarExpr:
-		elem, err := convertTypeSpec(t.X)
+		elem, err := c.convertTypeSpec(t.X)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert pointer: %w", err)
 		}
@@ -29,30 +110,33 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 			Elem: elem,
 		}, nil
 	case *ast.ArrayType:
-		elem, err := convertTypeSpec(t.Elt)
+		elem, err := c.convertTypeSpec(t.Elt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert array element: %w", err)
 		}
 		if t.Len == nil {
 			return Slice{Elem: elem}, nil
 		}
-		size, err := asIntLiteral(t.Len)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert array size: %w", err)
-		}
-		return Array{Elem: elem, Size: size}, nil
+		size, length := convertArrayLen(t.Len)
+		return Array{Elem: elem, Size: size, Len: length}, nil
+	case *ast.ParenExpr:
+		return c.convertTypeSpec(t.X)
+	case *ast.Ellipsis:
+		return nil, fmt.Errorf("unexpected ... outside of final function parameter")
+	case *ast.BadExpr:
+		return nil, fmt.Errorf("invalid type expression")
 	case *ast.MapType:
-		key, err := convertTypeSpec(t.Key)
+		key, err := c.convertTypeSpec(t.Key)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert map key: %w", err)
 		}
-		val, err := convertTypeSpec(t.Value)
+		val, err := c.convertTypeSpec(t.Value)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert map value: %w", err)
 		}
 		return Map{Key: key, Value: val}, nil
 	case *ast.ChanType:
-		elem, err := convertTypeSpec(t.Value)
+		elem, err := c.convertTypeSpec(t.Value)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert channel element: %w", err)
 		}
@@ -71,7 +155,7 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 		var s Struct
 		for fieldNum, field := range t.Fields.List {
 			if field.Names == nil {
-				t, err := convertTypeSpec(field.Type)
+				t, err := c.convertTypeSpec(field.Type)
 				if err != nil {
 					return nil, fmt.Errorf("failed to convert struct field %d: %w", fieldNum+1, err)
 				}
@@ -82,14 +166,18 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 						return nil, fmt.Errorf("failed to convert tag for struct field %d: %w", fieldNum+1, err)
 					}
 				}
+				doc, directives := fieldComments(field)
 				s.Fields = append(s.Fields, StructField{
-					Type: t,
-					Tag:  tag,
+					Position:   c.position(field.Pos(), field.End()),
+					Type:       t,
+					Tag:        tag,
+					Doc:        doc,
+					Directives: directives,
 				})
 				continue
 			}
 			for _, name := range field.Names {
-				t, err := convertTypeSpec(field.Type)
+				t, err := c.convertTypeSpec(field.Type)
 				if err != nil {
 					return nil, fmt.Errorf("failed to convert struct field %s: %w", name.Name, err)
 				}
@@ -100,20 +188,33 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 						return nil, fmt.Errorf("failed to convert tag for struct field %s: %w", name.Name, err)
 					}
 				}
+				doc, directives := fieldComments(field)
 				s.Fields = append(s.Fields, StructField{
-					Name: name.Name,
-					Type: t,
-					Tag:  tag,
+					Position:   c.position(name.Pos(), field.End()),
+					Name:       name.Name,
+					Type:       t,
+					Tag:        tag,
+					Doc:        doc,
+					Directives: directives,
 				})
 			}
 		}
 		return s, nil
 	case *ast.FuncType:
 		var params []FuncParam
+		variadic := false
 		if t.Params != nil {
 			for fieldNum, field := range t.Params.List {
+				paramType := field.Type
+				if ellipsis, ok := paramType.(*ast.Ellipsis); ok {
+					if fieldNum != len(t.Params.List)-1 || len(field.Names) > 1 {
+						return nil, fmt.Errorf("can only use ... with final function parameter")
+					}
+					paramType = &ast.ArrayType{Elt: ellipsis.Elt}
+					variadic = true
+				}
 				if field.Names == nil {
-					t, err := convertTypeSpec(field.Type)
+					t, err := c.convertTypeSpec(paramType)
 					if err != nil {
 						return nil, fmt.Errorf("failed to convert function parameter %d: %w", fieldNum+1, err)
 					}
@@ -123,7 +224,7 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 					continue
 				}
 				for _, name := range field.Names {
-					t, err := convertTypeSpec(field.Type)
+					t, err := c.convertTypeSpec(paramType)
 					if err != nil {
 						return nil, fmt.Errorf("failed to convert function parameter %s: %w", name.Name, err)
 					}
@@ -138,7 +239,7 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 		if t.Results != nil {
 			for fieldNum, field := range t.Results.List {
 				if field.Names == nil {
-					t, err := convertTypeSpec(field.Type)
+					t, err := c.convertTypeSpec(field.Type)
 					if err != nil {
 						return nil, fmt.Errorf("failed to convert function return value %d: %w", fieldNum+1, err)
 					}
@@ -148,7 +249,7 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 					continue
 				}
 				for _, name := range field.Names {
-					t, err := convertTypeSpec(field.Type)
+					t, err := c.convertTypeSpec(field.Type)
 					if err != nil {
 						return nil, fmt.Errorf("failed to convert function return value %s: %w", name.Name, err)
 					}
@@ -160,15 +261,24 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 			}
 		}
 		return Func{
-			Params:  params,
-			Results: results,
+			Params:   params,
+			Results:  results,
+			Variadic: variadic,
 		}, nil
 	case *ast.InterfaceType:
 		var i Interface
 		if t.Methods != nil {
 			for _, field := range t.Methods.List {
+				if field.Names == nil {
+					embed, err := c.convertTypeSpec(field.Type)
+					if err != nil {
+						return nil, fmt.Errorf("failed to convert embedded interface element: %w", err)
+					}
+					i.Embeds = append(i.Embeds, embed)
+					continue
+				}
 				for _, name := range field.Names {
-					t, err := convertTypeSpec(field.Type)
+					t, err := c.convertTypeSpec(field.Type)
 					if err != nil {
 						return nil, fmt.Errorf("failed to convert function parameter %s: %w", name.Name, err)
 					}
@@ -176,9 +286,13 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 					if !ok {
 						return nil, fmt.Errorf("interface method %s was somehow not a function type: %w", name.Name, err)
 					}
+					doc, directives := fieldComments(field)
 					i.Methods = append(i.Methods, InterfaceMethod{
-						Name: name.Name,
-						Type: f,
+						Position:   c.position(name.Pos(), field.End()),
+						Name:       name.Name,
+						Type:       f,
+						Doc:        doc,
+						Directives: directives,
 					})
 				}
 			}
@@ -188,73 +302,95 @@ func convertTypeSpec(spec ast.Expr) (Type, error) {
 	return nil, fmt.Errorf("unknown kind of type spec %#v", spec)
 }
 
-func asIntLiteral(expr ast.Expr) (int, error) {
-	t, ok := expr.(*ast.BasicLit)
-	if !ok {
-		return 0, fmt.Errorf("expression is not a basic literal")
+// position returns the position of the element spanning pos to end, or the zero Position if the converter has no file set.
+func (c converter) position(pos token.Pos, end token.Pos) Position {
+	if c.fileSet == nil {
+		return Position{}
 	}
-	if t.Kind != token.INT {
-		return 0, fmt.Errorf("expression is not a basic literal integer")
+	start, stop := c.fileSet.Position(pos), c.fileSet.Position(end)
+	return Position{
+		Path:      c.path,
+		Line:      start.Line,
+		Column:    start.Column,
+		EndLine:   stop.Line,
+		EndColumn: stop.Column,
 	}
-	return strconv.Atoi(t.Value)
 }
 
-func asStringLiteral(expr ast.Expr) (string, error) {
-	t, ok := expr.(*ast.BasicLit)
-	if !ok {
-		return "", fmt.Errorf("expression is not a basic literal")
+func (c converter) convertInstance(generic ast.Expr, indices []ast.Expr) (Type, error) {
+	typ, err := c.convertTypeSpec(generic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert generic type: %w", err)
 	}
-	if t.Kind != token.STRING {
-		return "", fmt.Errorf("expression is not a basic literal string")
+	switch typ.(type) {
+	case Ident, Selector:
+	default:
+		return nil, fmt.Errorf("expected generic type to be an identifier or selector, got %s", typ)
 	}
-	return strconv.Unquote(t.Value)
+	inst := Instance{Type: typ}
+	for _, index := range indices {
+		arg, err := c.convertTypeSpec(index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert type argument: %w", err)
+		}
+		inst.Args = append(inst.Args, arg)
+	}
+	return inst, nil
 }
 
-type funcVisitor func(ast.Node) ast.Visitor
-
-func (f funcVisitor) Visit(node ast.Node) ast.Visitor {
-	return f(node)
+// receiverTypeParams returns the type parameters introduced by a method receiver like *List[T],
+// as a field list without constraints.
+func receiverTypeParams(recv ast.Expr) *ast.FieldList {
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	var indices []ast.Expr
+	switch recv := recv.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{recv.Index}
+	case *ast.IndexListExpr:
+		indices = recv.Indices
+	default:
+		return nil
+	}
+	list := &ast.FieldList{}
+	for _, index := range indices {
+		if ident, ok := index.(*ast.Ident); ok {
+			list.List = append(list.List, &ast.Field{Names: []*ast.Ident{ident}})
+		}
+	}
+	return list
 }
 
-func findTypeAlias(fileSet *ast.File, pos token.Pos) (Type, error) {
-	done := false
-	var typ ast.Expr
-	var err error
+// unionTerms returns the terms of t if it is a Union, or t as a single term otherwise.
+func unionTerms(t Type) []Term {
+	if u, ok := t.(Union); ok {
+		return u.Terms
+	}
+	return []Term{{Type: t}}
+}
 
-	var search funcVisitor
-	search = funcVisitor(func(node ast.Node) ast.Visitor {
-		if done {
-			return nil
-		}
-		if node == nil {
-			return search
-		}
-		if node.Pos() > pos {
-			done = true
-			switch concrete := node.(type) {
-			case *ast.Ident:
-				typ = concrete
-				return nil
-			case *ast.SelectorExpr:
-				typ = concrete
-				return nil
-			default:
-				err = fmt.Errorf("type alias is not an identifier or selector")
-				return nil
-			}
-		}
-		return search
-	})
-	ast.Walk(search, fileSet)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find alias: %w", err)
+// convertArrayLen converts an array length expression.
+// Lengths that can be evaluated on their own are returned as a size.
+// Other lengths, like named constants and [...], are returned as source with a size of -1.
+func convertArrayLen(expr ast.Expr) (int, string) {
+	if _, ok := expr.(*ast.Ellipsis); ok {
+		return -1, "..."
 	}
-	if typ == nil {
-		return nil, fmt.Errorf("failed to find alias")
+	v := constant.ToInt(newConstEvaluator().eval(expr, 0))
+	if size, ok := constant.Int64Val(v); ok && v.Kind() == constant.Int && size >= 0 {
+		return int(size), ""
 	}
-	converted, err := convertTypeSpec(typ)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert identifier in alias: %w", err)
+	return -1, types.ExprString(expr)
+}
+
+func asStringLiteral(expr ast.Expr) (string, error) {
+	t, ok := expr.(*ast.BasicLit)
+	if !ok {
+		return "", fmt.Errorf("expression is not a basic literal")
 	}
-	return converted, nil
+	if t.Kind != token.STRING {
+		return "", fmt.Errorf("expression is not a basic literal string")
+	}
+	return strconv.Unquote(t.Value)
 }
@@ -0,0 +1,319 @@
+// Package enumgen generates methods for enum types: integer types with a block of constants, like
+//
+//	type Color int
+//
+//	const (
+//		ColorRed Color = iota
+//		ColorGreen
+//	)
+//
+// For a type T, the generated code contains String, IsValid, MarshalText, UnmarshalText, MarshalJSON and UnmarshalJSON methods,
+// along with ParseT to find a value by name and TValues to list all values.
+// The names used in text form are the constant names, with the Options applied.
+package enumgen
+
+import (
+	"flag"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"strings"
+	"unicode"
+
+	"github.com/PieterD/pkg/gadget"
+)
+
+// Transform changes the case and word separation of a constant name.
+type Transform string
+
+const (
+	None  Transform = ""      // Keep the name as is.
+	Lower Transform = "lower" // ColorRed becomes colorred.
+	Upper Transform = "upper" // ColorRed becomes COLORRED.
+	Snake Transform = "snake" // HTTPStatusOK becomes http_status_ok.
+	Kebab Transform = "kebab" // HTTPStatusOK becomes http-status-ok.
+)
+
+// ParseTransform parses the name of a Transform, like "snake".
+func ParseTransform(s string) (Transform, error) {
+	switch t := Transform(s); t {
+	case None, Lower, Upper, Snake, Kebab:
+		return t, nil
+	}
+	return None, fmt.Errorf("unknown transform '%s', expected one of lower, upper, snake or kebab", s)
+}
+
+// Apply transforms name.
+func (t Transform) Apply(name string) string {
+	switch t {
+	case Lower:
+		return strings.ToLower(name)
+	case Upper:
+		return strings.ToUpper(name)
+	case Snake:
+		return strings.ToLower(strings.Join(splitWords(name), "_"))
+	case Kebab:
+		return strings.ToLower(strings.Join(splitWords(name), "-"))
+	}
+	return name
+}
+
+// splitWords splits a Go identifier into words, keeping acronyms together:
+// HTTPStatusOK becomes HTTP, Status, OK.
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && runes[i] != '_' && !wordBoundary(runes, i) {
+			continue
+		}
+		if start < i {
+			words = append(words, string(runes[start:i]))
+		}
+		start = i
+		if i < len(runes) && runes[i] == '_' {
+			start = i + 1
+		}
+	}
+	return words
+}
+
+// wordBoundary returns true if a new word starts at runes[i].
+func wordBoundary(runes []rune, i int) bool {
+	prev, cur := runes[i-1], runes[i]
+	switch {
+	case prev == '_':
+		return false
+	case unicode.IsUpper(cur) && !unicode.IsUpper(prev):
+		return true
+	case unicode.IsUpper(cur) && unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+		return true
+	}
+	return false
+}
+
+// Options controls the names of the values in text form.
+type Options struct {
+	TrimPrefix string    // Removed from the start of constant names, like "Color" for ColorRed.
+	Transform  Transform // Applied after trimming the prefix.
+}
+
+// Name returns the text form of a constant name.
+func (opts Options) Name(constName string) string {
+	return opts.Transform.Apply(strings.TrimPrefix(constName, opts.TrimPrefix))
+}
+
+// Constants returns the constants of the named type.
+func Constants(consts []gadget.ConstDecl, typeName string) []gadget.ConstDecl {
+	var typed []gadget.ConstDecl
+	for _, c := range consts {
+		if c.Type == gadget.Ident(typeName) && c.Name != "_" {
+			typed = append(typed, c)
+		}
+	}
+	return typed
+}
+
+// Run is the enumgen command: it parses the enumgen flags from info.Args, and writes the code for the enum type
+// selected by info to <file>_<suffix>.go.
+func Run(info *gadget.Info) error {
+	fs := flag.NewFlagSet("enumgen", flag.ContinueOnError)
+	trimPrefix := fs.String("trimprefix", "", "prefix to trim from constant names")
+	transform := fs.String("transform", "", "transformation of constant names: lower, upper, snake or kebab")
+	suffix := fs.String("suffix", "enum", "suffix of the generated file name")
+	if err := info.ParseFlags(fs); err != nil {
+		return err
+	}
+	opts := Options{TrimPrefix: *trimPrefix}
+	var err error
+	opts.Transform, err = ParseTransform(*transform)
+	if err != nil {
+		return info.Errorf("%w", err)
+	}
+	pkg, err := info.OpenPackage()
+	if err != nil {
+		return fmt.Errorf("failed to open package: %w", err)
+	}
+	target, err := info.Target()
+	if err != nil {
+		return err
+	}
+	var typeName string
+	switch target.Kind {
+	case gadget.TypeTarget:
+		typeName = target.Type.Name
+	case gadget.ConstTarget:
+		id, ok := target.Consts[0].Type.(gadget.Ident)
+		if !ok {
+			return info.Errorf("expected const block to declare constants of a named type, found %s", target.Name())
+		}
+		typeName = string(id)
+	default:
+		return info.Errorf("expected go:generate directive to precede a type or const block, found %s %s", target.Kind, target.Name())
+	}
+	decl, ok := pkg.LookupType(typeName)
+	if !ok {
+		return info.Errorf("type %s is not declared in package %s", typeName, pkg.Name)
+	}
+	o, err := info.Output(*suffix)
+	if err != nil {
+		return fmt.Errorf("failed to create output: %w", err)
+	}
+	o.Generator = "enumgen"
+	if err := Generate(o, pkg, decl, Constants(pkg.Consts, typeName), opts); err != nil {
+		return err
+	}
+	return o.Write()
+}
+
+// value is a distinct value of the enum, along with its names.
+type value struct {
+	consts []gadget.ConstDecl // The constants with this value; the first determines the name used by String.
+	names  []string           // The text forms of the constants.
+}
+
+// Generate appends the enum methods and functions for the type declared by decl to o.
+// consts are the constants of the enum, in declaration order; see Constants.
+// decls is used to check that the underlying type of decl is an integer type.
+func Generate(o *gadget.Output, decls gadget.Decls, decl gadget.TypeDecl, consts []gadget.ConstDecl, opts Options) error {
+	if decl.Alias != nil {
+		return fmt.Errorf("%s: type %s is an alias", decl.Position, decl.Name)
+	}
+	u, ok := gadget.Comparer{Decls: decls}.Underlying(gadget.Ident(decl.Name))
+	if !ok || !isInteger(u) {
+		return fmt.Errorf("%s: type %s is not an integer type", decl.Position, decl.Name)
+	}
+	if len(consts) == 0 {
+		return fmt.Errorf("%s: no constants declared for type %s", decl.Position, decl.Name)
+	}
+	var values []*value
+	byName := make(map[string]gadget.ConstDecl)
+	for _, c := range consts {
+		if c.Value == nil || c.Value.Kind() != constant.Int {
+			return fmt.Errorf("%s: value of constant %s could not be evaluated", c.Position, c.Name)
+		}
+		name := opts.Name(c.Name)
+		if name == "" {
+			return fmt.Errorf("%s: constant %s has an empty name after trimming prefix '%s'", c.Position, c.Name, opts.TrimPrefix)
+		}
+		if other, ok := byName[name]; ok {
+			return fmt.Errorf("%s: constant %s has the same name '%s' as constant %s", c.Position, c.Name, name, other.Name)
+		}
+		byName[name] = c
+		var v *value
+		for _, existing := range values {
+			if constant.Compare(existing.consts[0].Value, token.EQL, c.Value) {
+				v = existing
+				break
+			}
+		}
+		if v == nil {
+			v = &value{}
+			values = append(values, v)
+		}
+		v.consts = append(v.consts, c)
+		v.names = append(v.names, name)
+	}
+
+	typ := decl.Name
+	strconvPkg := o.Import("strconv")
+	format := "FormatInt(int64(v), 10)"
+	if isUnsigned(u) {
+		format = "FormatUint(uint64(v), 10)"
+	}
+
+	o.Printf("// String returns the name of v, or %s(<number>) if v is not a valid %s.\n", typ, typ)
+	o.Printf("func (v %s) String() string {\n", typ)
+	o.Printf("switch v {\n")
+	for _, v := range values {
+		o.Printf("case %s:\nreturn %q\n", v.consts[0].Name, v.names[0])
+	}
+	o.Printf("}\n")
+	o.Printf("return %q + %s.%s + \")\"\n", typ+"(", strconvPkg, format)
+	o.Printf("}\n\n")
+
+	o.Printf("// IsValid returns true if v is one of the declared %s constants.\n", typ)
+	o.Printf("func (v %s) IsValid() bool {\n", typ)
+	o.Printf("switch v {\n")
+	o.Printf("case %s:\nreturn true\n", strings.Join(firstNames(values), ", "))
+	o.Printf("}\n")
+	o.Printf("return false\n")
+	o.Printf("}\n\n")
+
+	fmtPkg := o.Import("fmt")
+	o.Printf("// Parse%s returns the %s with the given name.\n", typ, typ)
+	o.Printf("func Parse%s(s string) (%s, error) {\n", typ, typ)
+	o.Printf("switch s {\n")
+	for _, v := range values {
+		for n, c := range v.consts {
+			o.Printf("case %q:\nreturn %s, nil\n", v.names[n], c.Name)
+		}
+	}
+	o.Printf("}\n")
+	o.Printf("return 0, %s.Errorf(\"invalid %s %%q\", s)\n", fmtPkg, typ)
+	o.Printf("}\n\n")
+
+	o.Printf("// %sValues returns all distinct %s values, in declaration order.\n", typ, typ)
+	o.Printf("func %sValues() []%s {\n", typ, typ)
+	o.Printf("return []%s{%s}\n", typ, strings.Join(firstNames(values), ", "))
+	o.Printf("}\n\n")
+
+	o.Printf("// MarshalText implements encoding.TextMarshaler.\n")
+	o.Printf("func (v %s) MarshalText() ([]byte, error) {\n", typ)
+	o.Printf("if !v.IsValid() {\n")
+	o.Printf("return nil, %s.Errorf(\"invalid %s %%s\", %s.%s)\n", fmtPkg, typ, strconvPkg, format)
+	o.Printf("}\n")
+	o.Printf("return []byte(v.String()), nil\n")
+	o.Printf("}\n\n")
+
+	o.Printf("// UnmarshalText implements encoding.TextUnmarshaler.\n")
+	o.Printf("func (v *%s) UnmarshalText(text []byte) error {\n", typ)
+	o.Printf("parsed, err := Parse%s(string(text))\n", typ)
+	o.Printf("if err != nil {\nreturn err\n}\n")
+	o.Printf("*v = parsed\n")
+	o.Printf("return nil\n")
+	o.Printf("}\n\n")
+
+	jsonPkg := o.Import("encoding/json")
+	o.Printf("// MarshalJSON implements json.Marshaler, encoding v as a string.\n")
+	o.Printf("func (v %s) MarshalJSON() ([]byte, error) {\n", typ)
+	o.Printf("text, err := v.MarshalText()\n")
+	o.Printf("if err != nil {\nreturn nil, err\n}\n")
+	o.Printf("return %s.Marshal(string(text))\n", jsonPkg)
+	o.Printf("}\n\n")
+
+	o.Printf("// UnmarshalJSON implements json.Unmarshaler, decoding v from a string.\n")
+	o.Printf("func (v *%s) UnmarshalJSON(data []byte) error {\n", typ)
+	o.Printf("var s string\n")
+	o.Printf("if err := %s.Unmarshal(data, &s); err != nil {\n", jsonPkg)
+	o.Printf("return %s.Errorf(\"invalid %s: %%w\", err)\n", fmtPkg, typ)
+	o.Printf("}\n")
+	o.Printf("return v.UnmarshalText([]byte(s))\n")
+	o.Printf("}\n\n")
+	return nil
+}
+
+func firstNames(values []*value) []string {
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = v.consts[0].Name
+	}
+	return names
+}
+
+func isInteger(t gadget.Type) bool {
+	switch t {
+	case gadget.Int, gadget.Int8, gadget.Int16, gadget.Int32, gadget.Int64, gadget.Rune:
+		return true
+	}
+	return isUnsigned(t)
+}
+
+func isUnsigned(t gadget.Type) bool {
+	switch t {
+	case gadget.Uint, gadget.Uint8, gadget.Uint16, gadget.Uint32, gadget.Uint64, gadget.Uintptr, gadget.Byte:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package enumgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PieterD/pkg/gadget"
+	"github.com/PieterD/pkg/gadget/gadgettest"
+)
+
+func TestTransform(t *testing.T) {
+	for _, test := range []struct {
+		transform Transform
+		in        string
+		expected  string
+	}{
+		{None, "StatusOK", "StatusOK"},
+		{Lower, "StatusOK", "statusok"},
+		{Upper, "StatusOK", "STATUSOK"},
+		{Snake, "HTTPStatusOK", "http_status_ok"},
+		{Snake, "DarkBlue", "dark_blue"},
+		{Snake, "Int64Value", "int64_value"},
+		{Kebab, "already_snake_Case", "already-snake-case"},
+		{Kebab, "ID", "id"},
+	} {
+		if got := test.transform.Apply(test.in); got != test.expected {
+			t.Errorf("%s(%s): expected %s, got %s", test.transform, test.in, test.expected, got)
+		}
+	}
+	if _, err := ParseTransform("camel"); err == nil {
+		t.Fatalf("expected error for unknown transform")
+	}
+}
+
+// TestGenerate_Example checks that the generated files in the example package are up to date.
+func TestGenerate_Example(t *testing.T) {
+	for _, file := range []string{"example/color.go", "example/status.go"} {
+		gadgettest.Golden(t, Run, gadgettest.Case{File: file}, "")
+	}
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	src := `package test
+
+type Name string
+
+const NameA Name = "a"
+
+type Empty int
+
+type Dup int
+
+const (
+	DupA Dup = iota
+	DupB
+)
+`
+	f, err := gadget.NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	for _, test := range []struct {
+		name     string
+		opts     Options
+		position string
+	}{
+		{"Name", Options{}, "test.go:3:"},
+		{"Empty", Options{}, "test.go:7:"},
+		{"Dup", Options{Transform: Upper, TrimPrefix: "DupA"}, "test.go:12:"},
+		{"Dup", Options{TrimPrefix: "Dup", Transform: Lower}, ""},
+	} {
+		decl, _ := f.LookupType(test.name)
+		err := Generate(gadget.NewOutput("test_enum.go", "test", nil), f, decl, Constants(f.Consts, test.name), test.opts)
+		if test.position == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), test.position) {
+			t.Errorf("%s: expected error at %s, got %v", test.name, test.position, err)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+// Package example contains enum types with methods generated by enumgen.
+package example
+
+//go:generate go run github.com/PieterD/pkg/gadget/cmd/enumgen -trimprefix=Color -transform=kebab
+type Color uint8
+
+const (
+	ColorRed Color = iota + 1
+	ColorGreen
+	ColorDarkBlue
+	// ColorDefault is an alias for ColorRed.
+	ColorDefault Color = ColorRed
+)
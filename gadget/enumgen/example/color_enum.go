@@ -0,0 +1,87 @@
+// Code generated by enumgen. DO NOT EDIT.
+
+package example
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// String returns the name of v, or Color(<number>) if v is not a valid Color.
+func (v Color) String() string {
+	switch v {
+	case ColorRed:
+		return "red"
+	case ColorGreen:
+		return "green"
+	case ColorDarkBlue:
+		return "dark-blue"
+	}
+	return "Color(" + strconv.FormatUint(uint64(v), 10) + ")"
+}
+
+// IsValid returns true if v is one of the declared Color constants.
+func (v Color) IsValid() bool {
+	switch v {
+	case ColorRed, ColorGreen, ColorDarkBlue:
+		return true
+	}
+	return false
+}
+
+// ParseColor returns the Color with the given name.
+func ParseColor(s string) (Color, error) {
+	switch s {
+	case "red":
+		return ColorRed, nil
+	case "default":
+		return ColorDefault, nil
+	case "green":
+		return ColorGreen, nil
+	case "dark-blue":
+		return ColorDarkBlue, nil
+	}
+	return 0, fmt.Errorf("invalid Color %q", s)
+}
+
+// ColorValues returns all distinct Color values, in declaration order.
+func ColorValues() []Color {
+	return []Color{ColorRed, ColorGreen, ColorDarkBlue}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v Color) MarshalText() ([]byte, error) {
+	if !v.IsValid() {
+		return nil, fmt.Errorf("invalid Color %s", strconv.FormatUint(uint64(v), 10))
+	}
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *Color) UnmarshalText(text []byte) error {
+	parsed, err := ParseColor(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding v as a string.
+func (v Color) MarshalJSON() ([]byte, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding v from a string.
+func (v *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid Color: %w", err)
+	}
+	return v.UnmarshalText([]byte(s))
+}
@@ -0,0 +1,54 @@
+package example
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestColor(t *testing.T) {
+	if s := ColorDarkBlue.String(); s != "dark-blue" {
+		t.Fatalf("expected dark-blue, got %s", s)
+	}
+	if s := Color(9).String(); s != "Color(9)" {
+		t.Fatalf("expected Color(9), got %s", s)
+	}
+	if Color(0).IsValid() || !ColorDefault.IsValid() {
+		t.Fatalf("unexpected validity")
+	}
+	expected := []Color{ColorRed, ColorGreen, ColorDarkBlue}
+	if values := ColorValues(); !reflect.DeepEqual(expected, values) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+	if c, err := ParseColor("default"); err != nil || c != ColorRed {
+		t.Fatalf("expected default to parse as red, got %v, %v", c, err)
+	}
+	if _, err := ParseColor("purple"); err == nil {
+		t.Fatalf("expected error for unknown color")
+	}
+}
+
+func TestColor_JSON(t *testing.T) {
+	in := map[string]Color{"a": ColorGreen, "b": ColorDarkBlue}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if string(b) != `{"a":"green","b":"dark-blue"}` {
+		t.Fatalf("unexpected json: %s", b)
+	}
+	var out map[string]Color
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("expected %v, got %v", in, out)
+	}
+	if _, err := json.Marshal(Color(0)); err == nil {
+		t.Fatalf("expected error marshaling invalid color")
+	}
+	var c Color
+	if err := json.Unmarshal([]byte(`3`), &c); err == nil {
+		t.Fatalf("expected error unmarshaling a number")
+	}
+}
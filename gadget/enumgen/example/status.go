@@ -0,0 +1,10 @@
+package example
+
+type Status int
+
+//go:generate go run github.com/PieterD/pkg/gadget/cmd/enumgen -transform=snake
+const (
+	StatusOK Status = iota
+	StatusHTTPError
+	StatusTimeout
+)
@@ -0,0 +1,85 @@
+// Code generated by enumgen. DO NOT EDIT.
+
+package example
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// String returns the name of v, or Status(<number>) if v is not a valid Status.
+func (v Status) String() string {
+	switch v {
+	case StatusOK:
+		return "status_ok"
+	case StatusHTTPError:
+		return "status_http_error"
+	case StatusTimeout:
+		return "status_timeout"
+	}
+	return "Status(" + strconv.FormatInt(int64(v), 10) + ")"
+}
+
+// IsValid returns true if v is one of the declared Status constants.
+func (v Status) IsValid() bool {
+	switch v {
+	case StatusOK, StatusHTTPError, StatusTimeout:
+		return true
+	}
+	return false
+}
+
+// ParseStatus returns the Status with the given name.
+func ParseStatus(s string) (Status, error) {
+	switch s {
+	case "status_ok":
+		return StatusOK, nil
+	case "status_http_error":
+		return StatusHTTPError, nil
+	case "status_timeout":
+		return StatusTimeout, nil
+	}
+	return 0, fmt.Errorf("invalid Status %q", s)
+}
+
+// StatusValues returns all distinct Status values, in declaration order.
+func StatusValues() []Status {
+	return []Status{StatusOK, StatusHTTPError, StatusTimeout}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v Status) MarshalText() ([]byte, error) {
+	if !v.IsValid() {
+		return nil, fmt.Errorf("invalid Status %s", strconv.FormatInt(int64(v), 10))
+	}
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *Status) UnmarshalText(text []byte) error {
+	parsed, err := ParseStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding v as a string.
+func (v Status) MarshalJSON() ([]byte, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding v from a string.
+func (v *Status) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid Status: %w", err)
+	}
+	return v.UnmarshalText([]byte(s))
+}
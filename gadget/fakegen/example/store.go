@@ -0,0 +1,28 @@
+// Package example contains an interface with a fake generated by fakegen.
+package example
+
+import (
+	"context"
+	"time"
+)
+
+type Value struct {
+	Data    []byte
+	Expires time.Time
+}
+
+// Closer is embedded in Store.
+type Closer interface {
+	Close() error
+}
+
+// Store is a key/value store.
+//
+//go:generate go run github.com/PieterD/pkg/gadget/cmd/fakegen
+type Store interface {
+	Closer
+	Get(ctx context.Context, key string) (Value, error)
+	Put(ctx context.Context, key string, value Value, ttl time.Duration) (replaced bool, err error)
+	Delete(context.Context, ...string)
+	Logf(format string, args ...interface{})
+}
@@ -0,0 +1,188 @@
+// Code generated by fakegen. DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FakeStore is a fake implementation of Store.
+// Set the Func fields to control the results of the methods; methods without a Func return zero values.
+type FakeStore struct {
+	CloseFunc  func() error                                                                                     // Called by Close, if set.
+	DeleteFunc func(context.Context, ...string)                                                                 // Called by Delete, if set.
+	GetFunc    func(ctx context.Context, key string) (Value, error)                                             // Called by Get, if set.
+	LogfFunc   func(format string, args ...interface{})                                                         // Called by Logf, if set.
+	PutFunc    func(ctx context.Context, key string, value Value, ttl time.Duration) (replaced bool, err error) // Called by Put, if set.
+
+	mu          sync.Mutex
+	callsClose  []FakeStoreCloseCall
+	callsDelete []FakeStoreDeleteCall
+	callsGet    []FakeStoreGetCall
+	callsLogf   []FakeStoreLogfCall
+	callsPut    []FakeStorePutCall
+}
+
+var _ Store = (*FakeStore)(nil)
+
+// FakeStoreCloseCall records the arguments of a call to Close.
+type FakeStoreCloseCall struct {
+}
+
+// Close records the call, and calls CloseFunc if it is set.
+func (fake *FakeStore) Close() (result0 error) {
+	fake.mu.Lock()
+	fake.callsClose = append(fake.callsClose, FakeStoreCloseCall{})
+	fn := fake.CloseFunc
+	fake.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	return fn()
+}
+
+// CloseCalls returns the arguments of all calls to Close, in order.
+func (fake *FakeStore) CloseCalls() []FakeStoreCloseCall {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]FakeStoreCloseCall(nil), fake.callsClose...)
+}
+
+// CloseCallCount returns the number of calls to Close.
+func (fake *FakeStore) CloseCallCount() int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return len(fake.callsClose)
+}
+
+// FakeStoreDeleteCall records the arguments of a call to Delete.
+type FakeStoreDeleteCall struct {
+	Arg0 context.Context
+	Arg1 []string
+}
+
+// Delete records the call, and calls DeleteFunc if it is set.
+func (fake *FakeStore) Delete(arg0 context.Context, arg1 ...string) {
+	fake.mu.Lock()
+	fake.callsDelete = append(fake.callsDelete, FakeStoreDeleteCall{Arg0: arg0, Arg1: arg1})
+	fn := fake.DeleteFunc
+	fake.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	fn(arg0, arg1...)
+}
+
+// DeleteCalls returns the arguments of all calls to Delete, in order.
+func (fake *FakeStore) DeleteCalls() []FakeStoreDeleteCall {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]FakeStoreDeleteCall(nil), fake.callsDelete...)
+}
+
+// DeleteCallCount returns the number of calls to Delete.
+func (fake *FakeStore) DeleteCallCount() int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return len(fake.callsDelete)
+}
+
+// FakeStoreGetCall records the arguments of a call to Get.
+type FakeStoreGetCall struct {
+	Ctx context.Context
+	Key string
+}
+
+// Get records the call, and calls GetFunc if it is set.
+func (fake *FakeStore) Get(ctx context.Context, key string) (result0 Value, result1 error) {
+	fake.mu.Lock()
+	fake.callsGet = append(fake.callsGet, FakeStoreGetCall{Ctx: ctx, Key: key})
+	fn := fake.GetFunc
+	fake.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	return fn(ctx, key)
+}
+
+// GetCalls returns the arguments of all calls to Get, in order.
+func (fake *FakeStore) GetCalls() []FakeStoreGetCall {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]FakeStoreGetCall(nil), fake.callsGet...)
+}
+
+// GetCallCount returns the number of calls to Get.
+func (fake *FakeStore) GetCallCount() int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return len(fake.callsGet)
+}
+
+// FakeStoreLogfCall records the arguments of a call to Logf.
+type FakeStoreLogfCall struct {
+	Format string
+	Args   []interface{}
+}
+
+// Logf records the call, and calls LogfFunc if it is set.
+func (fake *FakeStore) Logf(format string, args ...interface{}) {
+	fake.mu.Lock()
+	fake.callsLogf = append(fake.callsLogf, FakeStoreLogfCall{Format: format, Args: args})
+	fn := fake.LogfFunc
+	fake.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	fn(format, args...)
+}
+
+// LogfCalls returns the arguments of all calls to Logf, in order.
+func (fake *FakeStore) LogfCalls() []FakeStoreLogfCall {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]FakeStoreLogfCall(nil), fake.callsLogf...)
+}
+
+// LogfCallCount returns the number of calls to Logf.
+func (fake *FakeStore) LogfCallCount() int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return len(fake.callsLogf)
+}
+
+// FakeStorePutCall records the arguments of a call to Put.
+type FakeStorePutCall struct {
+	Ctx   context.Context
+	Key   string
+	Value Value
+	Ttl   time.Duration
+}
+
+// Put records the call, and calls PutFunc if it is set.
+func (fake *FakeStore) Put(ctx context.Context, key string, value Value, ttl time.Duration) (replaced bool, err error) {
+	fake.mu.Lock()
+	fake.callsPut = append(fake.callsPut, FakeStorePutCall{Ctx: ctx, Key: key, Value: value, Ttl: ttl})
+	fn := fake.PutFunc
+	fake.mu.Unlock()
+	if fn == nil {
+		return
+	}
+	return fn(ctx, key, value, ttl)
+}
+
+// PutCalls returns the arguments of all calls to Put, in order.
+func (fake *FakeStore) PutCalls() []FakeStorePutCall {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]FakeStorePutCall(nil), fake.callsPut...)
+}
+
+// PutCallCount returns the number of calls to Put.
+func (fake *FakeStore) PutCallCount() int {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return len(fake.callsPut)
+}
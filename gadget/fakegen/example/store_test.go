@@ -0,0 +1,74 @@
+package example
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFakeStore(t *testing.T) {
+	fake := &FakeStore{
+		GetFunc: func(ctx context.Context, key string) (Value, error) {
+			if key == "missing" {
+				return Value{}, errors.New("not found")
+			}
+			return Value{Data: []byte(key)}, nil
+		},
+	}
+	var store Store = fake
+	ctx := context.Background()
+
+	v, err := store.Get(ctx, "hello")
+	if err != nil || string(v.Data) != "hello" {
+		t.Fatalf("unexpected result %v, %v", v, err)
+	}
+	if _, err := store.Get(ctx, "missing"); err == nil {
+		t.Fatalf("expected error from GetFunc")
+	}
+	replaced, err := store.Put(ctx, "key", Value{}, time.Second)
+	if replaced || err != nil {
+		t.Fatalf("expected zero values without PutFunc, got %v, %v", replaced, err)
+	}
+	store.Delete(ctx, "a", "b")
+	store.Logf("%d", 1)
+
+	expectedGets := []FakeStoreGetCall{{Ctx: ctx, Key: "hello"}, {Ctx: ctx, Key: "missing"}}
+	if calls := fake.GetCalls(); !reflect.DeepEqual(expectedGets, calls) {
+		t.Fatalf("expected %v, got %v", expectedGets, calls)
+	}
+	expectedDeletes := []FakeStoreDeleteCall{{Arg0: ctx, Arg1: []string{"a", "b"}}}
+	if calls := fake.DeleteCalls(); !reflect.DeepEqual(expectedDeletes, calls) {
+		t.Fatalf("expected %v, got %v", expectedDeletes, calls)
+	}
+	expectedLogs := []FakeStoreLogfCall{{Format: "%d", Args: []interface{}{1}}}
+	if calls := fake.LogfCalls(); !reflect.DeepEqual(expectedLogs, calls) {
+		t.Fatalf("expected %v, got %v", expectedLogs, calls)
+	}
+	if n := fake.PutCallCount(); n != 1 {
+		t.Fatalf("expected 1 call to Put, got %d", n)
+	}
+	if n := fake.CloseCallCount(); n != 0 {
+		t.Fatalf("expected no calls to Close, got %d", n)
+	}
+}
+
+func TestFakeStore_Concurrent(t *testing.T) {
+	fake := &FakeStore{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fake.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := fake.CloseCallCount(); n != 1000 {
+		t.Fatalf("expected 1000 calls to Close, got %d", n)
+	}
+}
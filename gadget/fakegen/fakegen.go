@@ -0,0 +1,299 @@
+// Package fakegen generates fake implementations of interfaces for use in tests.
+//
+// For an interface Store with a method Get, the fake looks like
+//
+//	type FakeStore struct {
+//		GetFunc func(key string) (Value, error) // Called by Get, if set.
+//		...
+//	}
+//
+//	func (fake *FakeStore) Get(key string) (Value, error)  // Records the call, and calls GetFunc.
+//	func (fake *FakeStore) GetCalls() []FakeStoreGetCall   // Returns the arguments of all calls to Get.
+//	func (fake *FakeStore) GetCallCount() int              // Returns the number of calls to Get.
+//
+// If GetFunc is nil, Get returns zero values.
+// The fake is safe for concurrent use, as long as the Func fields are set before it is used.
+package fakegen
+
+import (
+	"flag"
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PieterD/pkg/gadget"
+)
+
+// receiver is the name of the receiver in the generated methods.
+const receiver = "fake"
+
+// Options controls the generated fake.
+type Options struct {
+	Name string // The name of the fake type. Defaults to Fake followed by the name of the interface.
+}
+
+// Run is the fakegen command: it parses the fakegen flags from info.Args, and writes a fake of the interface
+// selected by info to <file>_<suffix>.go.
+func Run(info *gadget.Info) error {
+	fs := flag.NewFlagSet("fakegen", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the fake type (default Fake<Interface>)")
+	suffix := fs.String("suffix", "fake", "suffix of the generated file name")
+	if err := info.ParseFlags(fs); err != nil {
+		return err
+	}
+	pkg, err := info.OpenPackage()
+	if err != nil {
+		return fmt.Errorf("failed to open package: %w", err)
+	}
+	typeName, _, err := info.GetType()
+	if err != nil {
+		return err
+	}
+	decl, ok := pkg.LookupType(typeName)
+	if !ok {
+		return info.Errorf("type %s is not declared in package %s", typeName, pkg.Name)
+	}
+	o, err := info.Output(*suffix)
+	if err != nil {
+		return fmt.Errorf("failed to create output: %w", err)
+	}
+	o.Generator = "fakegen"
+	if err := Generate(o, pkg, decl, Options{Name: *name}); err != nil {
+		return err
+	}
+	return o.Write()
+}
+
+// Generate appends a fake implementation of the interface declared by decl to o.
+// Methods of embedded interfaces are included. Interfaces embedded from other packages, like io.Closer,
+// are resolved by type checking the package, so decls must be a *gadget.Package for those.
+func Generate(o *gadget.Output, decls gadget.Decls, decl gadget.TypeDecl, opts Options) error {
+	if decl.Alias != nil {
+		return fmt.Errorf("%s: type %s is an alias", decl.Position, decl.Name)
+	}
+	if len(decl.TypeParams) > 0 {
+		return fmt.Errorf("%s: generic interface %s is not supported", decl.Position, decl.Name)
+	}
+	iface, ok := decl.Type.(gadget.Interface)
+	if !ok {
+		return fmt.Errorf("%s: type %s is not an interface", decl.Position, decl.Name)
+	}
+	ms := decls.InterfaceMethods(iface)
+	methods := make([]method, len(ms.Methods))
+	for i, m := range ms.Methods {
+		methods[i] = method{Method: m}
+	}
+	if len(ms.Unresolved) > 0 {
+		var err error
+		methods, err = resolveEmbeds(o, decls, decl, ms)
+		if err != nil {
+			return err
+		}
+	}
+	name := opts.Name
+	if name == "" {
+		name = "Fake" + capitalize(decl.Name)
+	}
+
+	members := map[string]string{"mu": "mutex"}
+	claim := func(member string, owner string) error {
+		if other, ok := members[member]; ok {
+			return fmt.Errorf("%s: generated %s for %s conflicts with %s", decl.Position, member, owner, other)
+		}
+		members[member] = owner
+		return nil
+	}
+	for _, method := range methods {
+		if err := claim(method.Name, "method "+method.Name); err != nil {
+			return err
+		}
+	}
+	for _, method := range methods {
+		for _, member := range []string{method.Name + "Func", method.Name + "Calls", method.Name + "CallCount", "calls" + method.Name} {
+			if err := claim(member, "method "+method.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	sync := o.Import("sync")
+	o.Printf("// %s is a fake implementation of %s.\n", name, decl.Name)
+	o.Printf("// Set the Func fields to control the results of the methods; methods without a Func return zero values.\n")
+	o.Printf("type %s struct {\n", name)
+	for _, method := range methods {
+		o.Printf("%sFunc %s // Called by %s, if set.\n", method.Name, method.typeString(o, method.Type), method.Name)
+	}
+	o.Printf("\nmu %s.Mutex\n", sync)
+	for _, method := range methods {
+		o.Printf("calls%s []%s\n", method.Name, callType(name, method.Name))
+	}
+	o.Printf("}\n\n")
+	o.Printf("var _ %s = (*%s)(nil)\n\n", decl.Name, name)
+	for _, method := range methods {
+		generateMethod(o, name, method)
+	}
+	return nil
+}
+
+// method is a method of the faked interface.
+type method struct {
+	gadget.Method
+	qualified bool // The types of the method are already qualified for the Output, as they come from another package.
+}
+
+// typeString returns the source representation of t, which is part of the method, within o.
+func (m method) typeString(o *gadget.Output, t gadget.Type) string {
+	if m.qualified {
+		return t.String()
+	}
+	return o.Type(t)
+}
+
+// resolveEmbeds returns the methods of the interface, including those of the interfaces it embeds from other packages.
+// The types of the methods from other packages are qualified by importing their packages into o.
+func resolveEmbeds(o *gadget.Output, decls gadget.Decls, decl gadget.TypeDecl, ms *gadget.MethodSet) ([]method, error) {
+	checker, ok := decls.(interface {
+		Check() (*gadget.Checked, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%s: interface %s embeds %s, which can only be resolved when generating from a package", decl.Position, decl.Name, ms.Unresolved[0])
+	}
+	checked, err := checker.Check()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to check package: %w", decl.Position, err)
+	}
+	checked.Qualifier = func(pkg *types.Package) string {
+		return o.Import(pkg.Path())
+	}
+	var methods []method
+	seen := make(map[string]bool)
+	for _, m := range ms.Methods {
+		seen[m.Name] = true
+		methods = append(methods, method{Method: m})
+	}
+	for _, embed := range ms.Unresolved {
+		embedded, err := checked.InterfaceMethods(decl.Position, embed)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to resolve %s embedded in %s: %w", decl.Position, embed, decl.Name, err)
+		}
+		for _, m := range embedded {
+			if !seen[m.Name] {
+				seen[m.Name] = true
+				methods = append(methods, method{Method: m, qualified: true})
+			}
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
+	return methods, nil
+}
+
+func generateMethod(o *gadget.Output, name string, method method) {
+	typ := method.Type
+	// Parameters and results are named after their declaration where possible. Generated names avoid the declared names,
+	// the names used in the body, and parameters whose fields in the call type would clash.
+	reserved := map[string]bool{receiver: true, "fn": true}
+	for _, param := range typ.Params {
+		reserved[param.Name] = true
+	}
+	for _, result := range typ.Results {
+		reserved[result.Name] = true
+	}
+	used := make(map[string]bool)
+	usedFields := make(map[string]bool)
+	fresh := func(format string, i int) string {
+		base := fmt.Sprintf(format, i)
+		name := base
+		for n := 2; reserved[name] || used[name] || usedFields[capitalize(name)]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		return name
+	}
+	params := make([]gadget.FuncParam, len(typ.Params))
+	fields := make([]string, len(typ.Params))
+	args := make([]string, len(typ.Params))
+	for i, param := range typ.Params {
+		paramName := param.Name
+		if paramName == "" || paramName == "_" || paramName == receiver || paramName == "fn" || usedFields[capitalize(paramName)] {
+			paramName = fresh("arg%d", i)
+		}
+		used[paramName] = true
+		usedFields[capitalize(paramName)] = true
+		params[i] = gadget.FuncParam{Name: paramName, Type: param.Type}
+		fields[i] = capitalize(paramName)
+		args[i] = paramName
+	}
+	if typ.Variadic && len(args) > 0 {
+		args[len(args)-1] += "..."
+	}
+	results := make([]gadget.FuncResult, len(typ.Results))
+	for i, result := range typ.Results {
+		resultName := result.Name
+		if resultName == "" || resultName == "_" || resultName == receiver || resultName == "fn" || used[resultName] {
+			resultName = fresh("result%d", i)
+		}
+		used[resultName] = true
+		results[i] = gadget.FuncResult{Name: resultName, Type: result.Type}
+	}
+	call := callType(name, method.Name)
+
+	o.Printf("// %s records the arguments of a call to %s.\n", call, method.Name)
+	o.Printf("type %s struct {\n", call)
+	for i, param := range params {
+		o.Printf("%s %s\n", fields[i], method.typeString(o, param.Type))
+	}
+	o.Printf("}\n\n")
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s.mu.Lock()\n", receiver)
+	fmt.Fprintf(&body, "%s.calls%s = append(%s.calls%s, %s{", receiver, method.Name, receiver, method.Name, call)
+	for i, param := range params {
+		if i > 0 {
+			body.WriteString(", ")
+		}
+		fmt.Fprintf(&body, "%s: %s", fields[i], param.Name)
+	}
+	body.WriteString("})\n")
+	fmt.Fprintf(&body, "fn := %s.%sFunc\n", receiver, method.Name)
+	fmt.Fprintf(&body, "%s.mu.Unlock()\n", receiver)
+	fmt.Fprintf(&body, "if fn == nil {\nreturn\n}\n")
+	if len(results) > 0 {
+		body.WriteString("return ")
+	}
+	fmt.Fprintf(&body, "fn(%s)", strings.Join(args, ", "))
+	o.Printf("// %s records the call, and calls %sFunc if it is set.\n", method.Name, method.Name)
+	signature := method.typeString(o, gadget.Func{
+		Params:   params,
+		Results:  results,
+		Variadic: typ.Variadic,
+	})
+	o.Printf("func (%s *%s) %s%s {\n%s\n}\n\n", receiver, name, method.Name, strings.TrimPrefix(signature, "func"), body.String())
+
+	o.Printf("// %sCalls returns the arguments of all calls to %s, in order.\n", method.Name, method.Name)
+	o.Printf("func (%s *%s) %sCalls() []%s {\n", receiver, name, method.Name, call)
+	o.Printf("%s.mu.Lock()\n", receiver)
+	o.Printf("defer %s.mu.Unlock()\n", receiver)
+	o.Printf("return append([]%s(nil), %s.calls%s...)\n", call, receiver, method.Name)
+	o.Printf("}\n\n")
+
+	o.Printf("// %sCallCount returns the number of calls to %s.\n", method.Name, method.Name)
+	o.Printf("func (%s *%s) %sCallCount() int {\n", receiver, name, method.Name)
+	o.Printf("%s.mu.Lock()\n", receiver)
+	o.Printf("defer %s.mu.Unlock()\n", receiver)
+	o.Printf("return len(%s.calls%s)\n", receiver, method.Name)
+	o.Printf("}\n\n")
+}
+
+// callType returns the name of the type recording the calls to a method.
+func callType(name string, method string) string {
+	return name + capitalize(method) + "Call"
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,202 @@
+package fakegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PieterD/pkg/gadget"
+	"github.com/PieterD/pkg/gadget/gadgettest"
+)
+
+// TestGenerate_Example checks that the generated fake in the example package is up to date.
+func TestGenerate_Example(t *testing.T) {
+	gadgettest.Golden(t, Run, gadgettest.Case{File: "example/store.go"}, "")
+}
+
+func TestGenerate_Options(t *testing.T) {
+	src := `package test
+
+type reader interface {
+	Read(p []byte) (n int, err error)
+}
+`
+	f, err := gadget.NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	decl, _ := f.LookupType("reader")
+	o := gadget.NewOutput("test_fake.go", "test", f.Scope())
+	if err := Generate(o, f, decl, Options{Name: "stubReader"}); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	b, err := o.Bytes()
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	for _, expected := range []string{
+		"type stubReader struct {",
+		"var _ reader = (*stubReader)(nil)",
+		"func (fake *stubReader) Read(p []byte) (n int, err error) {",
+		"type stubReaderReadCall struct {",
+	} {
+		if !strings.Contains(string(b), expected) {
+			t.Fatalf("expected generated code to contain %q, got:\n%s", expected, b)
+		}
+	}
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	src := `package test
+
+import "io"
+
+type NotInterface struct{}
+
+type External interface {
+	io.Reader
+}
+
+type Conflict interface {
+	Get() int
+	GetCalls() int
+}
+`
+	f, err := gadget.NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	for name, position := range map[string]string{
+		"NotInterface": "test.go:5:",
+		"External":     "test.go:7:",
+		"Conflict":     "test.go:11:",
+	} {
+		decl, _ := f.LookupType(name)
+		err := Generate(gadget.NewOutput("test_fake.go", "test", f.Scope()), f, decl, Options{})
+		if err == nil || !strings.HasPrefix(err.Error(), position) {
+			t.Errorf("%s: expected error at %s, got %v", name, position, err)
+		}
+	}
+}
+
+func TestGenerate_ExternalEmbeds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fakegen")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	src := `package test
+
+import "io"
+
+type Store interface {
+	io.Closer
+	Put(arg1 string, _ []byte, x int, X int) (result0 error)
+	Get(string) (result1 []byte, _ error)
+}
+`
+	path := filepath.Join(dir, "store.go")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	pkg, err := gadget.NewPackage(dir, "test")
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+	decl, _ := pkg.LookupType("Store")
+	scope, err := pkg.Scope(path)
+	if err != nil {
+		t.Fatalf("failed to get scope: %v", err)
+	}
+	o := gadget.NewOutput(filepath.Join(dir, "store_fake.go"), "test", scope)
+	if err := Generate(o, pkg, decl, Options{}); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	got, err := o.Bytes()
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	for _, want := range []string{
+		"func (fake *FakeStore) Close() (result0 error) {",
+		"func (fake *FakeStore) Put(arg1 string, arg1_2 []byte, x int, arg3 int) (result0 error) {",
+		"func (fake *FakeStore) Get(arg0 string) (result1 []byte, result1_2 error) {",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Logf("got: %s", got)
+			t.Fatalf("expected generated fake to contain %s", want)
+		}
+	}
+	if err := ioutil.WriteFile(o.Path, got, 0644); err != nil {
+		t.Fatalf("failed to write fake: %v", err)
+	}
+	pkg, err = gadget.NewPackage(dir, "test")
+	if err != nil {
+		t.Fatalf("failed to parse package with fake: %v", err)
+	}
+	checked, err := pkg.Check()
+	if err != nil {
+		t.Fatalf("failed to check package with fake: %v", err)
+	}
+	if len(checked.Errors) > 0 {
+		t.Fatalf("generated fake does not type check: %v", checked.Errors)
+	}
+}
+
+func TestGenerate_ExternalTypes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fakegen")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	// The methods of context.Context refer to time.Time, and time is not imported by the file.
+	// The alias of context must not be used for the types of its methods either.
+	src := `package test
+
+import stdctx "context"
+
+type Context interface {
+	stdctx.Context
+}
+`
+	path := filepath.Join(dir, "context.go")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	pkg, err := gadget.NewPackage(dir, "test")
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+	decl, _ := pkg.LookupType("Context")
+	scope, err := pkg.Scope(path)
+	if err != nil {
+		t.Fatalf("failed to get scope: %v", err)
+	}
+	o := gadget.NewOutput(filepath.Join(dir, "context_fake.go"), "test", scope)
+	if err := Generate(o, pkg, decl, Options{}); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	got, err := o.Bytes()
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	if !strings.Contains(string(got), "func (fake *FakeContext) Deadline() (deadline time.Time, ok bool) {") {
+		t.Logf("got: %s", got)
+		t.Fatalf("expected Deadline to return time.Time")
+	}
+	if err := ioutil.WriteFile(o.Path, got, 0644); err != nil {
+		t.Fatalf("failed to write fake: %v", err)
+	}
+	pkg, err = gadget.NewPackage(dir, "test")
+	if err != nil {
+		t.Fatalf("failed to parse package with fake: %v", err)
+	}
+	checked, err := pkg.Check()
+	if err != nil {
+		t.Fatalf("failed to check package with fake: %v", err)
+	}
+	if len(checked.Errors) > 0 {
+		t.Fatalf("generated fake does not type check: %v", checked.Errors)
+	}
+}
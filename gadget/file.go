@@ -1,31 +1,53 @@
 package gadget
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/parser"
+	"go/scanner"
 	"go/token"
 	"io"
 	"os"
+	"sort"
 )
 
 // File contains all the information we have about a parsed Go file.
 type File struct {
-	Path      string       // The path of the Go file this File represents.
-	Imports   []ImportDecl // The imports contained within the Go file.
-	Types     []TypeDecl   // The Type declarations contained within the Go file.
-	Funcs     []FuncDecl   // The Function declarations contained within the Go file.
-	HasErrors bool         // HasErrors is true if there was an invalid declaration was found.
+	Path       string       // The path of the Go file this File represents.
+	Package    string       // The package name declared by the Go file.
+	Imports    []ImportDecl // The imports contained within the Go file.
+	Types      []TypeDecl   // The Type declarations contained within the Go file.
+	Funcs      []FuncDecl   // The Function declarations contained within the Go file.
+	Consts     []ConstDecl  // The constant declarations contained within the Go file.
+	Vars       []VarDecl    // The variable declarations contained within the Go file.
+	Constraint string       // The build constraint expression of the Go file, like "linux && amd64". Empty if the file has none.
+	HasErrors  bool         // HasErrors is true if an invalid declaration was found. See NewFileTolerant for the details.
 }
 
-// Position represents a file:line location.
+// Position represents a file:line:column location, along with the end of the element it belongs to.
+// Lines and columns start at 1; a column of 0 means only the line is known.
 type Position struct {
-	Path string
-	Line int
+	Path      string
+	Line      int
+	Column    int // The column, in bytes.
+	EndLine   int // The line of the end of the element. Zero if unknown.
+	EndColumn int // The column immediately after the end of the element. Zero if unknown.
 }
 
+// String returns the position as path:line:column, or path:line if the column is unknown.
+// The end is not included.
 func (pos Position) String() string {
-	return fmt.Sprintf("%s:%d", pos.Path, pos.Line)
+	if pos.Column == 0 {
+		return fmt.Sprintf("%s:%d", pos.Path, pos.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.Path, pos.Line, pos.Column)
+}
+
+// End returns the position of the end of the element.
+func (pos Position) End() Position {
+	return Position{Path: pos.Path, Line: pos.EndLine, Column: pos.EndColumn}
 }
 
 type ImportDecl struct {
@@ -36,22 +58,104 @@ type ImportDecl struct {
 
 type TypeDecl struct {
 	Position
-	Name  string // The type name.
-	Type  Type   // The actual type definition. May be empty if the type declaration is an alias.
-	Alias Type   // The alias this declaration references. May be nil if the type declaration is not an alias. Is either Ident or Selector.
+	Name       string          // The type name.
+	Doc        string          // The doc comment of the declaration, without directives.
+	Directives Directives      // The directives in the doc comment, like //gadget:enum.
+	TypeParams []TypeParamDecl // The type parameters of a generic type.
+	Type       Type            // The actual type definition. May be empty if the type declaration is an alias.
+	Alias      Type            // The alias this declaration references. May be nil if the type declaration is not an alias.
 }
 
 type FuncDecl struct {
 	Position
-	Name string // The function name.
-	Recv string // The receiver type identifier.
-	Type Func   // The function type.
+	Name        string          // The function name.
+	Doc         string          // The doc comment of the declaration, without directives.
+	Directives  Directives      // The directives in the doc comment, like //gadget:enum.
+	Recv        string          // The receiver type identifier.
+	PointerRecv bool            // PointerRecv is true if the method has a pointer receiver, like *T.
+	TypeParams  []TypeParamDecl // The type parameters of a generic function, or the receiver type parameters of a method on a generic type.
+	Type        Func            // The function type.
 }
 
 // NewFile parses a Go file.
 // If reader is nil, the file at path is opened.
 // Otherwise, reader is taken to be the contents of the file.
+// The first syntax error or invalid declaration in the file is returned as an error.
 func NewFile(path string, reader io.Reader) (*File, error) {
+	f, _, err := newFile(token.NewFileSet(), path, reader, nil)
+	return f, err
+}
+
+// newFile parses a Go file like NewFile, into the given file set, and also returns the syntax tree.
+// If pkgConsts is not nil, the constants of the file are also added to it, to be evaluated along with those of sibling files.
+func newFile(fileSet *token.FileSet, path string, reader io.Reader, pkgConsts *constEvaluator) (*File, *ast.File, error) {
+	parsedFile, err := parseFile(fileSet, path, reader, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	b := newFileBuilder(fileSet, path, parsedFile)
+	b.pkgConsts = pkgConsts
+	if err := b.addConstraint(parsedFile); err != nil {
+		return nil, nil, err
+	}
+	for _, decl := range parsedFile.Decls {
+		if err := b.addDecl(decl); err != nil {
+			return nil, nil, err
+		}
+	}
+	return b.finish(), parsedFile, nil
+}
+
+// NewFileTolerant parses a Go file like NewFile, but does not give up on errors in the file.
+// All syntax errors and declarations that could not be converted are returned as diagnostics, ordered by position,
+// along with a File containing everything that could be parsed.
+// An error is only returned if the file could not be read, or is not recognizable as a Go file.
+func NewFileTolerant(path string, reader io.Reader) (*File, []PosError, error) {
+	fileSet := token.NewFileSet()
+	parsedFile, err := parseFile(fileSet, path, reader, parser.ParseComments|parser.AllErrors)
+	var diagnostics []PosError
+	var syntaxErrors scanner.ErrorList
+	if errors.As(err, &syntaxErrors) && parsedFile != nil && parsedFile.Name != nil {
+		for _, syntaxErr := range syntaxErrors {
+			diagnostics = append(diagnostics, PosError{
+				Position: Position{Path: path, Line: syntaxErr.Pos.Line, Column: syntaxErr.Pos.Column},
+				Err:      errors.New(syntaxErr.Msg),
+			})
+		}
+	} else if err != nil {
+		return nil, nil, err
+	}
+	b := newFileBuilder(fileSet, path, parsedFile)
+	if err := b.addConstraint(parsedFile); err != nil {
+		var posErr PosError
+		if !errors.As(err, &posErr) {
+			posErr = PosError{Position: b.base.position(parsedFile.Package, parsedFile.Name.End()), Err: err}
+		}
+		diagnostics = append(diagnostics, posErr)
+	}
+	for _, decl := range parsedFile.Decls {
+		if err := b.addDecl(decl); err != nil {
+			var posErr PosError
+			if !errors.As(err, &posErr) {
+				posErr = PosError{Position: b.base.position(decl.Pos(), decl.End()), Err: err}
+			}
+			diagnostics = append(diagnostics, posErr)
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Position, diagnostics[j].Position
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	f := b.finish()
+	if len(diagnostics) > 0 {
+		f.HasErrors = true
+	}
+	return f, diagnostics, nil
+}
+
+// parseFile parses the Go file at path, or the contents of reader if it is not nil.
+// The parsed file is returned along with any syntax errors, so that it may be used by NewFileTolerant.
+func parseFile(fileSet *token.FileSet, path string, reader io.Reader, mode parser.Mode) (*ast.File, error) {
 	if reader == nil {
 		h, err := os.Open(path)
 		if err != nil {
@@ -60,131 +164,272 @@ func NewFile(path string, reader io.Reader) (*File, error) {
 		defer h.Close()
 		reader = h
 	}
-	f := &File{
-		Path: path,
+	parsedFile, err := parser.ParseFile(fileSet, path, reader, mode)
+	if err != nil {
+		return parsedFile, fmt.Errorf("failed to parse file '%s': %w", path, err)
 	}
-	fileSet := token.NewFileSet()
-	parsedFile, err := parser.ParseFile(fileSet, path, reader, 0)
+	return parsedFile, nil
+}
+
+// fileBuilder converts the declarations of a parsed file.
+type fileBuilder struct {
+	f          *File
+	base       converter
+	consts     *constEvaluator
+	pkgConsts  *constEvaluator // Collects the constants of all files of a package, if not nil.
+	constGroup int
+}
+
+func newFileBuilder(fileSet *token.FileSet, path string, parsedFile *ast.File) *fileBuilder {
+	return &fileBuilder{
+		f: &File{
+			Path:    path,
+			Package: parsedFile.Name.Name,
+		},
+		base:       converter{fileSet: fileSet, path: path},
+		consts:     newConstEvaluator(),
+		constGroup: -1,
+	}
+}
+
+// finish evaluates the constants and returns the File.
+func (b *fileBuilder) finish() *File {
+	setConstValues(b.f.Consts, b.consts)
+	return b.f
+}
+
+// setConstValues evaluates the value of each constant using e.
+func setConstValues(consts []ConstDecl, e *constEvaluator) {
+	for i := range consts {
+		c := &consts[i]
+		if c.Name == "_" {
+			c.Value = constant.MakeUnknown()
+			continue
+		}
+		c.Value = e.value(c.Name)
+	}
+}
+
+// addConstraint sets the build constraint of the File.
+func (b *fileBuilder) addConstraint(parsedFile *ast.File) error {
+	expr, err := fileConstraint(parsedFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse file '%s': %w", path, err)
+		return errorAt(b.base.position(parsedFile.Package, parsedFile.Name.End()), "%w", err)
 	}
+	b.f.Constraint = constraintString(expr)
+	return nil
+}
 
-	for _, decl := range parsedFile.Decls {
-		pos := Position{
-			Path: path,
-			Line: fileSet.Position(decl.Pos()).Line,
+// errorAt returns an error tied to pos.
+func errorAt(pos Position, format string, args ...interface{}) error {
+	return PosError{Position: pos, Err: fmt.Errorf(format, args...)}
+}
+
+// addDecl adds a top level declaration to the File.
+// If a spec of a grouped declaration is invalid, the specs before it are still added.
+func (b *fileBuilder) addDecl(decl ast.Decl) error {
+	switch decl := decl.(type) {
+	case *ast.GenDecl:
+		var (
+			lastConstType   Type
+			lastConstValues []ast.Expr
+		)
+		if decl.Tok == token.CONST {
+			b.constGroup++
 		}
-		switch decl := decl.(type) {
-		case *ast.GenDecl:
-			for _, spec := range decl.Specs {
-				pos := Position{
-					Path: path,
-					Line: fileSet.Position(spec.Pos()).Line,
+		for specNum, spec := range decl.Specs {
+			pos := b.base.position(spec.Pos(), spec.End())
+			switch decl.Tok {
+			case token.TYPE:
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					return errorAt(pos, "expected *ast.TypeSpec, got %T", typeSpec)
 				}
-				switch decl.Tok {
-				case token.TYPE:
-					typeSpec, ok := spec.(*ast.TypeSpec)
-					if !ok {
-						return nil, fmt.Errorf("%s: expected *ast.TypeSpec, got %T", pos, typeSpec)
+				name := typeSpec.Name.Name
+				conv, typeParams, err := b.base.withTypeParams(typeSpec.TypeParams)
+				if err != nil {
+					return errorAt(pos, "failed to convert type parameters of type %s: %w", name, err)
+				}
+				var (
+					alias Type
+					typ   Type
+				)
+				if typeSpec.Assign.IsValid() {
+					alias, err = conv.convertTypeSpec(typeSpec.Type)
+					if err != nil {
+						return errorAt(pos, "failed to parse alias for type '%s': %w", name, err)
 					}
-					name := typeSpec.Name.Name
-					var (
-						alias Type
-						typ   Type
-					)
-					if typeSpec.Assign.IsValid() {
-						alias, err = findTypeAlias(parsedFile, typeSpec.Assign)
-						if err != nil {
-							return nil, fmt.Errorf("%s: failed to parse alias for type '%s': %w", pos, name, err)
-						}
-					} else {
-						typ, err = convertTypeSpec(typeSpec.Type)
-						if err != nil {
-							return nil, fmt.Errorf("%s: failed to convert type %s: %w", pos, name, err)
-						}
+				} else {
+					typ, err = conv.convertTypeSpec(typeSpec.Type)
+					if err != nil {
+						return errorAt(pos, "failed to convert type %s: %w", name, err)
 					}
-					f.Types = append(f.Types, TypeDecl{
+				}
+				docGroup := typeSpec.Doc
+				if docGroup == nil && !decl.Lparen.IsValid() {
+					docGroup = decl.Doc
+				}
+				doc, directives := parseComments(docGroup)
+				b.f.Types = append(b.f.Types, TypeDecl{
+					Position:   pos,
+					Name:       name,
+					Doc:        doc,
+					Directives: directives,
+					TypeParams: typeParams,
+					Type:       typ,
+					Alias:      alias,
+				})
+			case token.VAR:
+				varSpec, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					return errorAt(pos, "expected *ast.ValueSpec, got %T", varSpec)
+				}
+				var typ Type
+				if varSpec.Type != nil {
+					var err error
+					typ, err = b.base.convertTypeSpec(varSpec.Type)
+					if err != nil {
+						return errorAt(pos, "failed to convert type of var: %w", err)
+					}
+				}
+				for _, name := range varSpec.Names {
+					b.f.Vars = append(b.f.Vars, VarDecl{
 						Position: pos,
-						Name:     name,
+						Name:     name.Name,
 						Type:     typ,
-						Alias:    alias,
 					})
-				case token.VAR:
-					varSpec, ok := spec.(*ast.ValueSpec)
-					if !ok {
-						return nil, fmt.Errorf("%s: expected *ast.ValueSpec, got %T", pos, varSpec)
-					}
-				case token.CONST:
-					varSpec, ok := spec.(*ast.ValueSpec)
-					if !ok {
-						return nil, fmt.Errorf("%s: expected *ast.ValueSpec, got %T", pos, varSpec)
-					}
-				case token.IMPORT:
-					importSpec, ok := spec.(*ast.ImportSpec)
-					if !ok {
-						return nil, fmt.Errorf("%s: expected *ast.ImportSpec, got %T", pos, importSpec)
+				}
+			case token.CONST:
+				varSpec, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					return errorAt(pos, "expected *ast.ValueSpec, got %T", varSpec)
+				}
+				// A spec without values repeats the type and values of the previous spec.
+				if varSpec.Values != nil {
+					lastConstType = nil
+					if varSpec.Type != nil {
+						var err error
+						lastConstType, err = b.base.convertTypeSpec(varSpec.Type)
+						if err != nil {
+							return errorAt(pos, "failed to convert type of const: %w", err)
+						}
 					}
-					impName := ""
-					if importSpec.Name != nil {
-						impName = importSpec.Name.Name
+					lastConstValues = varSpec.Values
+				}
+				if len(varSpec.Names) > len(lastConstValues) {
+					return errorAt(pos, "missing value in const declaration")
+				}
+				for nameNum, name := range varSpec.Names {
+					cs := constSpec{
+						expr: lastConstValues[nameNum],
+						typ:  lastConstType,
+						iota: specNum,
 					}
-					impPath, err := asStringLiteral(importSpec.Path)
-					if err != nil {
-						return nil, fmt.Errorf("%s: failed to parse import path: %w", pos, err)
+					b.consts.add(name.Name, cs)
+					if b.pkgConsts != nil {
+						b.pkgConsts.add(name.Name, cs)
 					}
-					f.Imports = append(f.Imports, ImportDecl{
+					b.f.Consts = append(b.f.Consts, ConstDecl{
 						Position: pos,
-						Name:     impName,
-						Path:     impPath,
+						Name:     name.Name,
+						Type:     lastConstType,
+						Iota:     specNum,
+						Group:    b.constGroup,
 					})
 				}
-			}
-		case *ast.FuncDecl:
-			recv := ""
-			if decl.Recv != nil && len(decl.Recv.List) != 0 {
-				if len(decl.Recv.List) > 1 {
-					return nil, fmt.Errorf("%s: multiple method receivers", pos)
-				}
-				typ, err := convertTypeSpec(decl.Recv.List[0].Type)
-				if err != nil {
-					return nil, fmt.Errorf("%s: failed to convert method receiver type: %w", pos, err)
+			case token.IMPORT:
+				importSpec, ok := spec.(*ast.ImportSpec)
+				if !ok {
+					return errorAt(pos, "expected *ast.ImportSpec, got %T", importSpec)
 				}
-				ptr, ok := typ.(Pointer)
-				if ok {
-					typ = ptr.Elem
+				impName := ""
+				if importSpec.Name != nil {
+					impName = importSpec.Name.Name
 				}
-				id, ok := typ.(Ident)
-				if !ok {
-					return nil, fmt.Errorf("%s: method receiver type is not Identifier or *Identifier", pos)
+				impPath, err := asStringLiteral(importSpec.Path)
+				if err != nil {
+					return errorAt(pos, "failed to parse import path: %w", err)
 				}
-				recv = id.String()
+				b.f.Imports = append(b.f.Imports, ImportDecl{
+					Position: pos,
+					Name:     impName,
+					Path:     impPath,
+				})
+			}
+		}
+	case *ast.FuncDecl:
+		pos := b.base.position(decl.Pos(), decl.End())
+		recv := ""
+		pointerRecv := false
+		conv, typeParams, err := b.base.withTypeParams(decl.Type.TypeParams)
+		if err != nil {
+			return errorAt(pos, "failed to convert type parameters: %w", err)
+		}
+		if decl.Recv != nil && len(decl.Recv.List) != 0 {
+			if len(decl.Recv.List) > 1 {
+				return errorAt(pos, "multiple method receivers")
+			}
+			recvExpr := decl.Recv.List[0].Type
+			conv, typeParams, err = conv.withTypeParams(receiverTypeParams(recvExpr))
+			if err != nil {
+				return errorAt(pos, "failed to convert receiver type parameters: %w", err)
 			}
-			typ, err := convertTypeSpec(decl.Type)
+			typ, err := conv.convertTypeSpec(recvExpr)
 			if err != nil {
-				return nil, fmt.Errorf("%s: failed to convert function type: %w", pos, err)
+				return errorAt(pos, "failed to convert method receiver type: %w", err)
 			}
-			t, ok := typ.(Func)
+			ptr, ok := typ.(Pointer)
+			if ok {
+				typ = ptr.Elem
+				pointerRecv = true
+			}
+			if inst, ok := typ.(Instance); ok {
+				typ = inst.Type
+			}
+			id, ok := typ.(Ident)
 			if !ok {
-				return nil, fmt.Errorf("%s: function declaration type is somehow not a function type", pos)
+				return errorAt(pos, "method receiver type is not Identifier or *Identifier")
 			}
-			f.Funcs = append(f.Funcs, FuncDecl{
-				Position: pos,
-				Name:     decl.Name.Name,
-				Recv:     recv,
-				Type:     t,
-			})
-		case *ast.BadDecl:
-			f.HasErrors = true
+			recv = id.String()
 		}
+		typ, err := conv.convertTypeSpec(decl.Type)
+		if err != nil {
+			return errorAt(pos, "failed to convert function type: %w", err)
+		}
+		t, ok := typ.(Func)
+		if !ok {
+			return errorAt(pos, "function declaration type is somehow not a function type")
+		}
+		doc, directives := parseComments(decl.Doc)
+		b.f.Funcs = append(b.f.Funcs, FuncDecl{
+			Position:    pos,
+			Name:        decl.Name.Name,
+			Doc:         doc,
+			Directives:  directives,
+			Recv:        recv,
+			PointerRecv: pointerRecv,
+			TypeParams:  typeParams,
+			Type:        t,
+		})
+	case *ast.BadDecl:
+		b.f.HasErrors = true
 	}
-
-	return f, nil
+	return nil
 }
 
 // GetMethods fetches the methods belonging to the given type identifier.
 func (f *File) GetMethods(typeName string) map[string]Func {
+	return getMethods(f.Funcs, typeName)
+}
+
+// GetTypes fetches all non-alias types.
+func (f *File) GetTypes() map[string]Type {
+	return getTypes(f.Types)
+}
+
+func getMethods(funcs []FuncDecl, typeName string) map[string]Func {
 	decls := make(map[string]Func)
-	for _, fun := range f.Funcs {
+	for _, fun := range funcs {
 		if fun.Recv == typeName {
 			decls[fun.Name] = fun.Type
 		}
@@ -195,10 +440,9 @@ func (f *File) GetMethods(typeName string) map[string]Func {
 	return decls
 }
 
-// GetTypes fetches all non-alias types.
-func (f *File) GetTypes() map[string]Type {
+func getTypes(types []TypeDecl) map[string]Type {
 	decls := make(map[string]Type)
-	for _, decl := range f.Types {
+	for _, decl := range types {
 		if decl.Type == nil {
 			continue
 		}
@@ -1,7 +1,9 @@
 package gadget
 
 import (
+	"go/constant"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -14,17 +16,17 @@ func TestNewFile(t *testing.T) {
 
 	expectedImports := []ImportDecl{
 		{
-			Position: Position{Path: path, Line: 4},
+			Position: Position{Path: path, Line: 4, Column: 2, EndLine: 4, EndColumn: 7},
 			Name:     "",
 			Path:     "fmt",
 		},
 		{
-			Position: Position{Path: path, Line: 5},
+			Position: Position{Path: path, Line: 5, Column: 2, EndLine: 5, EndColumn: 6},
 			Name:     "",
 			Path:     "io",
 		},
 		{
-			Position: Position{Path: path, Line: 6},
+			Position: Position{Path: path, Line: 6, Column: 2, EndLine: 6, EndColumn: 13},
 			Name:     ".",
 			Path:     "strconv",
 		},
@@ -32,17 +34,18 @@ func TestNewFile(t *testing.T) {
 
 	expectedTypes := []TypeDecl{
 		{
-			Position: Position{Path: path, Line: 10},
-			Name:     "ExaType",
-			Type:     Slice{Elem: Map{Key: Int, Value: Struct{Fields: []StructField{{Name: "Err", Type: Error, Tag: "tag"}}}}},
+			Position:   Position{Path: path, Line: 10, Column: 6, EndLine: 12, EndColumn: 2},
+			Name:       "ExaType",
+			Directives: Directives{{Tool: "go", Name: "generate", Args: "go run ./"}},
+			Type:       Slice{Elem: Map{Key: Int, Value: Struct{Fields: []StructField{{Position: Position{Path: path, Line: 11, Column: 2, EndLine: 11, EndColumn: 17}, Name: "Err", Type: Error, Tag: "tag"}}}}},
 		},
 		{
-			Position: Position{Path: path, Line: 14},
+			Position: Position{Path: path, Line: 14, Column: 6, EndLine: 14, EndColumn: 27},
 			Name:     "Alias",
 			Alias:    Selector{Left: "io", Right: "ReadWriter"},
 		},
 		{
-			Position: Position{Path: path, Line: 20},
+			Position: Position{Path: path, Line: 20, Column: 6, EndLine: 20, EndColumn: 14},
 			Name:     "Smoo",
 			Type:     Int,
 		},
@@ -50,18 +53,33 @@ func TestNewFile(t *testing.T) {
 
 	expectedFuncs := []FuncDecl{
 		{
-			Position: Position{Path: path, Line: 16},
+			Position: Position{Path: path, Line: 16, Column: 1, EndLine: 18, EndColumn: 2},
 			Name:     "String",
 			Recv:     "ExaType",
 			Type:     Func{Results: []FuncResult{{Type: String}}},
 		},
 		{
-			Position: Position{Path: path, Line: 30},
+			Position: Position{Path: path, Line: 30, Column: 1, EndLine: 32, EndColumn: 2},
 			Name:     "hello",
 			Type:     Func{},
 		},
 	}
 
+	var expectedConsts []ConstDecl
+	for i, name := range []string{"A", "B", "C", "D", "E"} {
+		end := 3
+		if i == 0 {
+			end = 15
+		}
+		expectedConsts = append(expectedConsts, ConstDecl{
+			Position: Position{Path: path, Line: 23 + i, Column: 2, EndLine: 23 + i, EndColumn: end},
+			Name:     name,
+			Type:     Ident("Smoo"),
+			Value:    constant.MakeInt64(int64(i)),
+			Iota:     i,
+		})
+	}
+
 	if !reflect.DeepEqual(expectedImports, f.Imports) {
 		t.Logf("want: %#v", expectedImports)
 		t.Logf(" got: %#v", f.Imports)
@@ -80,6 +98,16 @@ func TestNewFile(t *testing.T) {
 		t.Fatalf("invalid funcs")
 	}
 
+	if !reflect.DeepEqual(expectedConsts, f.Consts) {
+		t.Logf("want: %#v", expectedConsts)
+		t.Logf(" got: %#v", f.Consts)
+		t.Fatalf("invalid consts")
+	}
+
+	if len(f.Vars) != 0 {
+		t.Fatalf("expected no vars, got %#v", f.Vars)
+	}
+
 	expectedMethods := map[string]Func{
 		"String": {Results: []FuncResult{{Type: String}}},
 	}
@@ -91,7 +119,7 @@ func TestNewFile(t *testing.T) {
 	}
 
 	expectedGetTypes := map[string]Type{
-		"ExaType": Slice{Elem: Map{Key: Int, Value: Struct{Fields: []StructField{{Name: "Err", Type: Error, Tag: "tag"}}}}},
+		"ExaType": Slice{Elem: Map{Key: Int, Value: Struct{Fields: []StructField{{Position: Position{Path: path, Line: 11, Column: 2, EndLine: 11, EndColumn: 17}, Name: "Err", Type: Error, Tag: "tag"}}}}},
 		"Smoo":    Int,
 	}
 	types := f.GetTypes()
@@ -101,3 +129,178 @@ func TestNewFile(t *testing.T) {
 		t.Fatalf("invalid methods")
 	}
 }
+
+func TestNewFile_Generics(t *testing.T) {
+	src := `package test
+
+type Number interface {
+	~int | ~int64 | float64
+}
+
+type List[T any] struct {
+	Items []T
+	Next  *List[T]
+}
+
+type Pair[K comparable, V Number] struct {
+	Key   K
+	Value V
+}
+
+type IntList = List[int]
+
+func (l *List[T]) Push(item T) {}
+
+func Map[S ~[]E, E any, R any](s S, f func(E) R) []R {
+	return nil
+}
+`
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+
+	expectedTypes := []TypeDecl{
+		{
+			Position: Position{Path: "test.go", Line: 3, Column: 6, EndLine: 5, EndColumn: 2},
+			Name:     "Number",
+			Type: Interface{Embeds: []Type{Union{Terms: []Term{
+				{Tilde: true, Type: Int},
+				{Tilde: true, Type: Int64},
+				{Type: Float64},
+			}}}},
+		},
+		{
+			Position:   Position{Path: "test.go", Line: 7, Column: 6, EndLine: 10, EndColumn: 2},
+			Name:       "List",
+			TypeParams: []TypeParamDecl{{Name: "T", Constraint: Ident("any")}},
+			Type: Struct{Fields: []StructField{
+				{Position: Position{Path: "test.go", Line: 8, Column: 2, EndLine: 8, EndColumn: 11}, Name: "Items", Type: Slice{Elem: TypeParam("T")}},
+				{Position: Position{Path: "test.go", Line: 9, Column: 2, EndLine: 9, EndColumn: 16}, Name: "Next", Type: Pointer{Elem: Instance{Type: Ident("List"), Args: []Type{TypeParam("T")}}}},
+			}},
+		},
+		{
+			Position: Position{Path: "test.go", Line: 12, Column: 6, EndLine: 15, EndColumn: 2},
+			Name:     "Pair",
+			TypeParams: []TypeParamDecl{
+				{Name: "K", Constraint: Ident("comparable")},
+				{Name: "V", Constraint: Ident("Number")},
+			},
+			Type: Struct{Fields: []StructField{
+				{Position: Position{Path: "test.go", Line: 13, Column: 2, EndLine: 13, EndColumn: 9}, Name: "Key", Type: TypeParam("K")},
+				{Position: Position{Path: "test.go", Line: 14, Column: 2, EndLine: 14, EndColumn: 9}, Name: "Value", Type: TypeParam("V")},
+			}},
+		},
+		{
+			Position: Position{Path: "test.go", Line: 17, Column: 6, EndLine: 17, EndColumn: 25},
+			Name:     "IntList",
+			Alias:    Instance{Type: Ident("List"), Args: []Type{Int}},
+		},
+	}
+	if !reflect.DeepEqual(expectedTypes, f.Types) {
+		t.Logf("want: %#v", expectedTypes)
+		t.Logf(" got: %#v", f.Types)
+		t.Fatalf("invalid types")
+	}
+
+	expectedFuncs := []FuncDecl{
+		{
+			Position:    Position{Path: "test.go", Line: 19, Column: 1, EndLine: 19, EndColumn: 34},
+			Name:        "Push",
+			Recv:        "List",
+			PointerRecv: true,
+			TypeParams:  []TypeParamDecl{{Name: "T"}},
+			Type:        Func{Params: []FuncParam{{Name: "item", Type: TypeParam("T")}}},
+		},
+		{
+			Position: Position{Path: "test.go", Line: 21, Column: 1, EndLine: 23, EndColumn: 2},
+			Name:     "Map",
+			TypeParams: []TypeParamDecl{
+				{Name: "S", Constraint: Union{Terms: []Term{{Tilde: true, Type: Slice{Elem: TypeParam("E")}}}}},
+				{Name: "E", Constraint: Ident("any")},
+				{Name: "R", Constraint: Ident("any")},
+			},
+			Type: Func{
+				Params: []FuncParam{
+					{Name: "s", Type: TypeParam("S")},
+					{Name: "f", Type: Func{
+						Params:  []FuncParam{{Type: TypeParam("E")}},
+						Results: []FuncResult{{Type: TypeParam("R")}},
+					}},
+				},
+				Results: []FuncResult{{Type: Slice{Elem: TypeParam("R")}}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(expectedFuncs, f.Funcs) {
+		t.Logf("want: %#v", expectedFuncs)
+		t.Logf(" got: %#v", f.Funcs)
+		t.Fatalf("invalid funcs")
+	}
+
+	if s := f.Types[1].Type.String(); s != "struct{Items []T; Next *List[T]}" {
+		t.Fatalf("unexpected string for generic struct: %s", s)
+	}
+}
+
+func TestNewFileTolerant(t *testing.T) {
+	src := `package test
+
+type Good struct {
+	Name string
+}
+
+type Bad map[string]
+
+type Other int
+
+func Fine() {}
+
+func broken() {
+	x :=
+}
+`
+	if _, err := NewFile("test.go", strings.NewReader(src)); err == nil {
+		t.Fatalf("expected NewFile to fail")
+	}
+	f, diagnostics, err := NewFileTolerant("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	if !f.HasErrors {
+		t.Fatalf("expected HasErrors to be set")
+	}
+	if len(diagnostics) == 0 {
+		t.Fatalf("expected diagnostics")
+	}
+	if got := diagnostics[0].Error(); got != "test.go:7:6: failed to convert type Bad: failed to convert map value: invalid type expression" {
+		t.Fatalf("unexpected first diagnostic: %s", got)
+	}
+	var types []string
+	for _, typ := range f.Types {
+		types = append(types, typ.Name)
+	}
+	if !reflect.DeepEqual(types, []string{"Good", "Other"}) {
+		t.Fatalf("unexpected types: %v", types)
+	}
+	var funcs []string
+	for _, fun := range f.Funcs {
+		funcs = append(funcs, fun.Name)
+	}
+	if !reflect.DeepEqual(funcs, []string{"Fine", "broken"}) {
+		t.Fatalf("unexpected funcs: %v", funcs)
+	}
+}
+
+func TestPosition_String(t *testing.T) {
+	pos := Position{Path: "test.go", Line: 3, Column: 6, EndLine: 5, EndColumn: 2}
+	if s := pos.String(); s != "test.go:3:6" {
+		t.Fatalf("unexpected position: %s", s)
+	}
+	if s := pos.End().String(); s != "test.go:5:2" {
+		t.Fatalf("unexpected end position: %s", s)
+	}
+	if s := (Position{Path: "test.go", Line: 3}).String(); s != "test.go:3" {
+		t.Fatalf("unexpected position without column: %s", s)
+	}
+}
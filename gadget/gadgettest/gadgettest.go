@@ -0,0 +1,289 @@
+// Package gadgettest runs gadget generators in tests, and compares the files they write against golden files.
+//
+// A generator is run as if by go generate: the working directory is the directory of the file,
+// the GOFILE, GOLINE and other environment variables are set, and the Info is obtained through gadget.Generate.
+// Files written through gadget.Output are captured instead of written to disk.
+//
+//	func TestGenerate(t *testing.T) {
+//		gadgettest.Golden(t, run, gadgettest.Case{File: "testdata/color.go"}, "testdata")
+//	}
+//
+// Run go test with -gadget.update to write the golden files.
+package gadgettest
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PieterD/pkg/gadget"
+)
+
+// Update is set by the -gadget.update flag of go test. If true, Golden writes golden files instead of comparing against them.
+// The flag is prefixed so that it does not clash with an -update flag of the test itself.
+var Update = flag.Bool("gadget.update", false, "update the golden files of gadget generators")
+
+// Generator is a generator under test. It receives the Info that gadget.Generate would return under go generate.
+type Generator func(info *gadget.Info) error
+
+// Case describes a single go generate invocation.
+type Case struct {
+	File   string   // The path of the Go file containing the go:generate directive, usually in testdata.
+	Source string   // The contents of the Go file. If set, File is not read; only its base name is used, in a temporary directory.
+	Line   int      // The line of the go:generate directive. Defaults to the first go:generate directive in the file.
+	Args   []string // The arguments passed to the generator. If nil, they are taken from the go:generate directive.
+	OS     string   // The value of GOOS. Defaults to the OS the test runs on.
+	Arch   string   // The value of GOARCH. Defaults to the architecture the test runs on.
+}
+
+// Run runs the generator for the case, and returns the files it wrote through gadget.Output, keyed by their file name.
+// The working directory and environment are changed while the generator runs, so Run must not be used in parallel tests.
+func Run(gen Generator, c Case) (map[string][]byte, error) {
+	path := c.File
+	if c.Source != "" {
+		dir, err := ioutil.TempDir("", "gadgettest")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp dir: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		path = filepath.Join(dir, filepath.Base(c.File))
+		if err := ioutil.WriteFile(path, []byte(c.Source), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write source: %w", err)
+		}
+	}
+	line := c.Line
+	if line == 0 {
+		var err error
+		line, err = findDirective(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f, err := gadget.NewFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+	env := map[string]string{
+		"GOOS":      c.OS,
+		"GOARCH":    c.Arch,
+		"GOPACKAGE": f.Package,
+		"GOFILE":    filepath.Base(path),
+		"GOLINE":    strconv.Itoa(line),
+		"DOLLAR":    "$",
+	}
+	if env["GOOS"] == "" {
+		env["GOOS"] = runtime.GOOS
+	}
+	if env["GOARCH"] == "" {
+		env["GOARCH"] = runtime.GOARCH
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	if err := os.Chdir(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to change to package directory: %w", err)
+	}
+	defer os.Chdir(wd)
+	defer setEnv(env)()
+	info, err := gadget.Generate()
+	if err != nil {
+		return nil, err
+	}
+	info.Args = c.Args
+	if info.Args == nil {
+		info.Args, err = directiveArgs(info.Directive, env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", info.Position(), err)
+		}
+	}
+	files := make(map[string][]byte)
+	info.WriteFile = func(path string, data []byte) error {
+		name, err := filepath.Rel(".", path)
+		if err != nil || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("generated file '%s' is outside of the package directory", path)
+		}
+		files[filepath.ToSlash(name)] = append([]byte(nil), data...)
+		return nil
+	}
+	if err := gen(info); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Golden runs the generator for the case, and compares every file it writes against the golden file
+// with the same name followed by .golden, in goldenDir.
+// If goldenDir is empty, the files are compared against those with the same name in the directory of the case file,
+// to check that generated code checked in next to it is up to date.
+// With the -gadget.update flag, the golden files are written instead.
+func Golden(t testing.TB, gen Generator, c Case, goldenDir string) {
+	t.Helper()
+	files, err := Run(gen, c)
+	if err != nil {
+		t.Fatalf("failed to run generator: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("generator did not write any files")
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		goldenPath := filepath.Join(goldenDir, filepath.FromSlash(name)+".golden")
+		if goldenDir == "" {
+			goldenPath = filepath.Join(filepath.Dir(c.File), filepath.FromSlash(name))
+		}
+		if *Update {
+			if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+				t.Fatalf("failed to create golden directory: %v", err)
+			}
+			if err := ioutil.WriteFile(goldenPath, files[name], 0644); err != nil {
+				t.Fatalf("failed to update golden file: %v", err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(goldenPath)
+		if err != nil {
+			t.Errorf("failed to read golden file for %s (run go test -gadget.update to create it): %v", name, err)
+			continue
+		}
+		if diff := Diff(want, files[name]); diff != "" {
+			t.Errorf("%s differs from %s (run go test -gadget.update to accept):\n%s", name, goldenPath, diff)
+		}
+	}
+}
+
+// Diff returns a description of the first difference between want and got, with some context, or an empty string if they are equal.
+func Diff(want []byte, got []byte) string {
+	if bytes.Equal(want, got) {
+		return ""
+	}
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	n := 0
+	for n < len(wantLines) && n < len(gotLines) && wantLines[n] == gotLines[n] {
+		n++
+	}
+	var diff strings.Builder
+	for i := n - 2; i < n; i++ {
+		if i >= 0 {
+			fmt.Fprintf(&diff, "  %4d  %s\n", i+1, wantLines[i])
+		}
+	}
+	for i := n; i < n+3 && i < len(wantLines); i++ {
+		fmt.Fprintf(&diff, "- %4d  %s\n", i+1, wantLines[i])
+	}
+	for i := n; i < n+3 && i < len(gotLines); i++ {
+		fmt.Fprintf(&diff, "+ %4d  %s\n", i+1, gotLines[i])
+	}
+	return diff.String()
+}
+
+// findDirective returns the line of the first go:generate directive in the file at path.
+func findDirective(path string) (int, error) {
+	h, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file '%s': %w", path, err)
+	}
+	defer h.Close()
+	scanner := bufio.NewScanner(h)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.HasPrefix(scanner.Text(), "//go:generate ") {
+			return n, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read file '%s': %w", path, err)
+	}
+	return 0, fmt.Errorf("no go:generate directive in '%s'", path)
+}
+
+// directiveArgs returns the arguments go generate passes to the command of a go:generate directive.
+// Like go generate, words are separated by spaces and tabs, double-quoted words are unquoted, and environment variables are expanded.
+// The command itself is left out, including go run, its flags and the package it runs.
+func directiveArgs(directive string, env map[string]string) ([]string, error) {
+	if !strings.HasPrefix(directive, "//go:generate ") {
+		return nil, nil
+	}
+	line := strings.TrimPrefix(directive, "//go:generate ")
+	var words []string
+	for line = strings.TrimLeft(line, " \t"); line != ""; line = strings.TrimLeft(line, " \t") {
+		if line[0] == '"' {
+			end := 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("unterminated quoted string in go:generate directive")
+			}
+			word, err := strconv.Unquote(line[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string in go:generate directive: %w", err)
+			}
+			words = append(words, word)
+			line = line[end+1:]
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+		words = append(words, line[:end])
+		line = line[end:]
+	}
+	for i, word := range words {
+		words[i] = os.Expand(word, func(key string) string {
+			if value, ok := env[key]; ok {
+				return value
+			}
+			return os.Getenv(key)
+		})
+	}
+	if len(words) >= 2 && words[0] == "go" && words[1] == "run" {
+		words = words[2:]
+		for len(words) > 0 && strings.HasPrefix(words[0], "-") {
+			words = words[1:]
+		}
+	}
+	if len(words) > 0 {
+		words = words[1:]
+	}
+	return words, nil
+}
+
+// setEnv sets environment variables, and returns a function restoring their previous values.
+func setEnv(env map[string]string) func() {
+	old := make(map[string]*string)
+	for key, value := range env {
+		if prev, ok := os.LookupEnv(key); ok {
+			old[key] = &prev
+		} else {
+			old[key] = nil
+		}
+		os.Setenv(key, value)
+	}
+	return func() {
+		for key, value := range old {
+			if value == nil {
+				os.Unsetenv(key)
+				continue
+			}
+			os.Setenv(key, *value)
+		}
+	}
+}
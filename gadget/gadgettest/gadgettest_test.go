@@ -0,0 +1,99 @@
+package gadgettest
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PieterD/pkg/gadget"
+	"github.com/PieterD/pkg/gadget/enumgen"
+)
+
+func TestGolden(t *testing.T) {
+	Golden(t, enumgen.Run, Case{File: "testdata/color.go"}, "testdata")
+	Golden(t, enumgen.Run, Case{File: "../enumgen/example/status.go"}, "")
+}
+
+func TestRun(t *testing.T) {
+	src := `package shapes
+
+//go:generate enumgen
+type Shape uint8
+
+const (
+	Circle Shape = iota + 1
+	Square
+)
+`
+	var seen *gadget.Info
+	var inDir bool
+	files, err := Run(func(info *gadget.Info) error {
+		seen = info
+		_, err := os.Stat("shape.go")
+		inDir = err == nil
+		return enumgen.Run(info)
+	}, Case{File: "shape.go", Source: src, OS: "plan9", Arch: "386"})
+	if err != nil {
+		t.Fatalf("failed to run generator: %v", err)
+	}
+	if seen.File != "shape.go" || seen.Line != 3 || seen.Package != "shapes" || seen.OS != "plan9" || seen.Arch != "386" || seen.Directive != "//go:generate enumgen" {
+		t.Fatalf("unexpected info: %#v", seen)
+	}
+	if !inDir {
+		t.Fatalf("expected generator to run in the directory of the file")
+	}
+	if _, err := os.Stat("testdata"); err != nil {
+		t.Fatalf("expected working directory to be restored: %v", err)
+	}
+	if len(files) != 1 || !strings.Contains(string(files["shape_enum.go"]), `case Circle:`) {
+		t.Fatalf("unexpected files: %v", files)
+	}
+
+	_, err = Run(func(info *gadget.Info) error {
+		return fmt.Errorf("failed at %s", info.Position())
+	}, Case{File: "shape.go", Source: src, Line: 6})
+	if err == nil || !strings.HasSuffix(err.Error(), "shape.go:6") {
+		t.Fatalf("expected generator error, got %v", err)
+	}
+	if _, err := Run(enumgen.Run, Case{File: "shape.go", Source: "package shapes\n"}); err == nil {
+		t.Fatalf("expected error for file without directive")
+	}
+}
+
+func TestDirectiveArgs(t *testing.T) {
+	env := map[string]string{"GOFILE": "color.go"}
+	for directive, want := range map[string][]string{
+		"//go:generate enumgen":                                        {},
+		"//go:generate enumgen -trimprefix=Color\t-suffix=enum":        {"-trimprefix=Color", "-suffix=enum"},
+		"//go:generate go run -mod=mod example.com/enumgen -a $GOFILE": {"-a", "color.go"},
+		`//go:generate enumgen "-comment=a \"b\"" -x`:                  {`-comment=a "b"`, "-x"},
+		"const (": nil,
+	} {
+		got, err := directiveArgs(directive, env)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", directive, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Logf("want: %q", want)
+			t.Logf(" got: %q", got)
+			t.Fatalf("unexpected arguments for %s", directive)
+		}
+	}
+	if _, err := directiveArgs(`//go:generate enumgen "-x`, env); err == nil {
+		t.Fatalf("expected error for unterminated quoted string")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if diff := Diff([]byte("a\nb\n"), []byte("a\nb\n")); diff != "" {
+		t.Fatalf("expected no diff, got %q", diff)
+	}
+	want := "     1  a\n     2  b\n-    3  c\n-    4  \n+    3  x\n+    4  \n"
+	if diff := Diff([]byte("a\nb\nc\n"), []byte("a\nb\nx\n")); diff != want {
+		t.Logf("want: %q", want)
+		t.Logf(" got: %q", diff)
+		t.Fatalf("unexpected diff")
+	}
+}
@@ -0,0 +1,10 @@
+package colors
+
+//go:generate enumgen -trimprefix=Color
+type Color int
+
+const (
+	ColorRed Color = iota
+	ColorGreen
+	ColorBlue
+)
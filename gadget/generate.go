@@ -1,20 +1,34 @@
 package gadget
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 )
 
 // Info contains the information about a go generate call.
 type Info struct {
-	Arch    string
-	OS      string
-	Package string
-	File    string
-	Line    int
+	Arch      string
+	OS        string
+	Package   string
+	File      string
+	Line      int
+	Directive string   // The source line of the go:generate directive.
+	Args      []string // The arguments passed to the generator, like os.Args[1:]: the program name is intentionally left out, so Args can be passed to flag.FlagSet.Parse.
+	Name      string   // The name of the declaration to generate for. If set, Target selects it instead of the declaration following Line.
+	Tags      []string // Additional build tags used to select the files of the package; see BuildContext.
+
+	WriteFile func(path string, data []byte) error // Passed on to the Outputs created by Output. If nil, files are written to disk.
 
 	file *File
+	pkg  *Package
 }
 
 // Generate returns the information about the current go generate call.
@@ -28,6 +42,97 @@ func Generate() (*Info, error) {
 	return i, nil
 }
 
+// NewInfo returns the Info for running a generator outside of go generate,
+// as if it was invoked by a go:generate directive at the given line of file, with the given arguments.
+// Arch and OS are those gadget is running on, and Package is the package declared by the file.
+// If line is 0, set Name to select the declaration to generate for.
+func NewInfo(file string, line int, args []string) (*Info, error) {
+	pkgName, _, err := readHeader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package name: %w", err)
+	}
+	i := &Info{
+		Arch:    runtime.GOARCH,
+		OS:      runtime.GOOS,
+		Package: pkgName,
+		File:    file,
+		Line:    line,
+		Args:    args,
+	}
+	if line > 0 {
+		directive, err := readLine(file, line)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directive: %w", err)
+		}
+		i.Directive = directive
+	}
+	return i, nil
+}
+
+// FromArgs returns the Info for running a generator outside of go generate, configured by command line arguments:
+//
+//	-file path      the Go file to generate for
+//	-line n         the line of the go:generate directive; the declaration following it is selected
+//	-type name      the name of the declaration to generate for, instead of -line
+//	-goos, -goarch  the target platform, defaulting to the one gadget is running on
+//	-tags list      a comma-separated list of additional build tags
+//
+// Arguments following the flags, usually separated by --, are the arguments of the generator.
+func FromArgs(args []string) (*Info, error) {
+	fs := flag.NewFlagSet("gadget", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	file := fs.String("file", "", "the Go file to generate for")
+	line := fs.Int("line", 0, "the line of the go:generate directive")
+	name := fs.String("type", "", "the name of the declaration to generate for, instead of -line")
+	goos := fs.String("goos", runtime.GOOS, "the target GOOS")
+	goarch := fs.String("goarch", runtime.GOARCH, "the target GOARCH")
+	tags := fs.String("tags", "", "a comma-separated list of additional build tags")
+	err := fs.Parse(args)
+	if err == nil {
+		switch {
+		case *file == "":
+			err = fmt.Errorf("missing -file")
+		case (*line > 0) == (*name != ""):
+			err = fmt.Errorf("expected exactly one of -line and -type")
+		}
+	}
+	if err != nil {
+		var usage strings.Builder
+		fs.SetOutput(&usage)
+		fs.PrintDefaults()
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, fmt.Errorf("%w\nusage outside of go generate: [flags] -- [generator arguments]\n%s", err, usage.String())
+		}
+		return nil, fmt.Errorf("invalid arguments: %w\nusage outside of go generate: [flags] -- [generator arguments]\n%s", err, usage.String())
+	}
+	i, err := NewInfo(*file, *line, fs.Args())
+	if err != nil {
+		return nil, err
+	}
+	i.Name = *name
+	i.OS = *goos
+	i.Arch = *goarch
+	if *tags != "" {
+		i.Tags = strings.Split(*tags, ",")
+	}
+	return i, nil
+}
+
+// Load returns the Info for the current execution of a generator.
+// When run by go generate, this is the same as Generate.
+// Otherwise the Info is built from the command line arguments, as described by FromArgs,
+// so that a generator can also be run with go run or in a debugger.
+func Load() (*Info, error) {
+	if _, ok := os.LookupEnv("GOFILE"); ok {
+		return Generate()
+	}
+	i, err := FromArgs(os.Args[1:])
+	if err != nil {
+		return nil, fmt.Errorf("not run by go generate: %w", err)
+	}
+	return i, nil
+}
+
 func (i *Info) generate() error {
 	i.Arch = os.Getenv("GOARCH")
 	if i.Arch == "" {
@@ -58,6 +163,76 @@ func (i *Info) generate() error {
 		return fmt.Errorf("invalid GOLINE environment variable '%s': %w", lineString, err)
 	}
 	i.Line = line
+	i.Args = os.Args[1:]
+	directive, err := readLine(i.File, i.Line)
+	if err != nil {
+		return fmt.Errorf("failed to read go:generate directive: %w", err)
+	}
+	i.Directive = directive
+	return nil
+}
+
+// readLine returns the given line of the file at path, without the line terminator.
+func readLine(path string, line int) (string, error) {
+	h, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file '%s': %w", path, err)
+	}
+	defer h.Close()
+	scanner := bufio.NewScanner(h)
+	for n := 1; scanner.Scan(); n++ {
+		if n == line {
+			return strings.TrimSuffix(scanner.Text(), "\r"), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read file '%s': %w", path, err)
+	}
+	return "", fmt.Errorf("file '%s' has no line %d", path, line)
+}
+
+// Position returns the position of the go:generate directive.
+func (i *Info) Position() Position {
+	return Position{Path: i.File, Line: i.Line}
+}
+
+// PosError is an error tied to a position in a Go file.
+type PosError struct {
+	Position
+	Err error
+}
+
+func (e PosError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Position, e.Err)
+}
+
+func (e PosError) Unwrap() error {
+	return e.Err
+}
+
+// Errorf returns an error tied to the position of the go:generate directive,
+// so that it is reported the way compiler errors are.
+func (i *Info) Errorf(format string, args ...interface{}) error {
+	return PosError{Position: i.Position(), Err: fmt.Errorf(format, args...)}
+}
+
+// ParseFlags parses the generator arguments into fs.
+// fs is switched to ContinueOnError, and parse errors are tied to the position of the go:generate directive.
+// Arguments remaining after the flags are available through fs.Args.
+func (i *Info) ParseFlags(fs *flag.FlagSet) error {
+	fs.Init(fs.Name(), flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	if err := fs.Parse(i.Args); err != nil {
+		var usage strings.Builder
+		fs.SetOutput(&usage)
+		fs.PrintDefaults()
+		fs.SetOutput(nil)
+		if errors.Is(err, flag.ErrHelp) {
+			return i.Errorf("%w\nusage of %s:\n%s", err, fs.Name(), usage.String())
+		}
+		return i.Errorf("invalid arguments: %w\nusage of %s:\n%s", err, fs.Name(), usage.String())
+	}
+	fs.SetOutput(nil)
 	return nil
 }
 
@@ -71,16 +246,42 @@ func (i *Info) Open() (*File, error) {
 	return file, nil
 }
 
+// OpenPackage will parse all files of the package the Info refers to.
+// Unlike Open, this makes declarations in sibling files visible.
+// Files excluded by build constraints for the OS, Arch and Tags of the Info are skipped; see BuildContext.
+// The File the Info refers to is always opened as part of the package, even if it is excluded or a test file.
+// If it is a test file, the other test files of the package are opened as well.
+func (i *Info) OpenPackage() (*Package, error) {
+	ctx := i.BuildContext()
+	pkg, err := newPackage(filepath.Dir(i.File), i.Package, &ctx, filepath.Base(i.File))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse package: %w", err)
+	}
+	file := pkg.File(filepath.Join(filepath.Dir(i.File), filepath.Base(i.File)))
+	if file == nil {
+		return nil, fmt.Errorf("file '%s' is not part of package '%s'", i.File, i.Package)
+	}
+	i.file = file
+	i.pkg = pkg
+	return pkg, nil
+}
+
 // GetType returns the name and type of the type selected to generate for.
-// If we could not find the type we are generating for, GetType returns an error.
+// The type is the first declaration following the go:generate directive, as found by Target.
+// If that declaration is not a type, or is an alias, GetType returns an error.
 func (i *Info) GetType() (string, Type, error) {
 	if i.file == nil {
 		return "", nil, fmt.Errorf("Info.GetType called before Info.Open")
 	}
-	for _, typ := range i.file.Types {
-		if typ.Line == i.Line+1 {
-			return typ.Name, typ.Type, nil
-		}
+	target, err := i.Target()
+	if err != nil {
+		return "", nil, err
+	}
+	if target.Kind != TypeTarget {
+		return "", nil, i.Errorf("expected go:generate directive to precede a type declaration, found %s %s at %s", target.Kind, target.Name(), target.Position)
+	}
+	if target.Type.Alias != nil {
+		return "", nil, i.Errorf("expected go:generate directive to precede a type declaration, found alias %s at %s", target.Type.Name, target.Position)
 	}
-	return "", nil, fmt.Errorf("unable to find type we are generating for")
+	return target.Type.Name, target.Type.Type, nil
 }
@@ -0,0 +1,170 @@
+package gadget
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// setEnv sets environment variables, and returns a function restoring their previous values.
+func setEnv(t *testing.T, env map[string]string) func() {
+	t.Helper()
+	old := make(map[string]*string)
+	for key, value := range env {
+		if prev, ok := os.LookupEnv(key); ok {
+			old[key] = &prev
+		} else {
+			old[key] = nil
+		}
+		if err := os.Setenv(key, value); err != nil {
+			t.Fatalf("failed to set %s: %v", key, err)
+		}
+	}
+	return func() {
+		for key, value := range old {
+			if value == nil {
+				os.Unsetenv(key)
+				continue
+			}
+			os.Setenv(key, *value)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	path := filepath.Join("example", "type.go")
+	defer setEnv(t, map[string]string{
+		"GOARCH":    "amd64",
+		"GOOS":      "linux",
+		"GOPACKAGE": "main",
+		"GOFILE":    path,
+		"GOLINE":    "9",
+		"DOLLAR":    "$",
+	})()
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"generator", "-type=ExaType", "-output", "out.go", "extra"}
+
+	info, err := Generate()
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	if info.Directive != "//go:generate go run ./" {
+		t.Fatalf("unexpected directive: %q", info.Directive)
+	}
+	if info.Position() != (Position{Path: path, Line: 9}) {
+		t.Fatalf("unexpected position: %v", info.Position())
+	}
+
+	fs := flag.NewFlagSet("generator", flag.ExitOnError)
+	typeName := fs.String("type", "", "type to generate for")
+	output := fs.String("output", "", "output file")
+	if err := info.ParseFlags(fs); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	if *typeName != "ExaType" || *output != "out.go" || strings.Join(fs.Args(), " ") != "extra" {
+		t.Fatalf("unexpected flags: type=%q output=%q args=%v", *typeName, *output, fs.Args())
+	}
+
+	info.Args = []string{"-unknown"}
+	err = info.ParseFlags(flag.NewFlagSet("generator", flag.ExitOnError))
+	var posErr PosError
+	if !errors.As(err, &posErr) || posErr.Position != info.Position() {
+		t.Fatalf("expected positioned error, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), path+":9: invalid arguments: flag provided but not defined: -unknown") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info.Args = []string{"-help"}
+	if err := info.ParseFlags(fs); !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("expected help error, got %v", err)
+	}
+
+	if err := info.Errorf("bad type %s", "X"); err.Error() != path+":9: bad type X" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGenerate_MissingEnv(t *testing.T) {
+	defer setEnv(t, map[string]string{"GOFILE": ""})()
+	if _, err := Generate(); err == nil {
+		t.Fatalf("expected error outside of go generate")
+	}
+}
+
+func TestFromArgs(t *testing.T) {
+	path := filepath.Join("example", "type.go")
+	info, err := FromArgs([]string{"-file", path, "-type", "Smoo", "-goos", "plan9", "-tags", "a,b", "--", "-trimprefix", "X"})
+	if err != nil {
+		t.Fatalf("failed to build info: %v", err)
+	}
+	if info.Package != "main" || info.OS != "plan9" || info.Arch != runtime.GOARCH || info.Name != "Smoo" || strings.Join(info.Tags, " ") != "a b" {
+		t.Fatalf("unexpected info: %#v", info)
+	}
+	if strings.Join(info.Args, " ") != "-trimprefix X" {
+		t.Fatalf("unexpected args: %v", info.Args)
+	}
+	if _, err := info.Open(); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	name, typ, err := info.GetType()
+	if err != nil {
+		t.Fatalf("failed to get type: %v", err)
+	}
+	if name != "Smoo" || typ != Int {
+		t.Fatalf("unexpected type %s %s", name, typ)
+	}
+
+	info, err = FromArgs([]string{"-file", path, "-line", "9"})
+	if err != nil {
+		t.Fatalf("failed to build info: %v", err)
+	}
+	if info.Directive != "//go:generate go run ./" || info.OS != runtime.GOOS {
+		t.Fatalf("unexpected info: %#v", info)
+	}
+	if _, err := info.Open(); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if name, _, err := info.GetType(); err != nil || name != "ExaType" {
+		t.Fatalf("expected ExaType, got %s, %v", name, err)
+	}
+
+	for _, args := range [][]string{
+		{"-type", "Smoo"},
+		{"-file", path},
+		{"-file", path, "-line", "9", "-type", "Smoo"},
+		{"-file", filepath.Join("example", "missing.go"), "-line", "9"},
+	} {
+		if _, err := FromArgs(args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+	if _, err := FromArgs([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("expected help error, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"generator", "-file", filepath.Join("example", "type.go"), "-type", "Missing"}
+	if gofile, ok := os.LookupEnv("GOFILE"); ok {
+		os.Unsetenv("GOFILE")
+		defer os.Setenv("GOFILE", gofile)
+	}
+	info, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load info: %v", err)
+	}
+	if _, err := info.Open(); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if _, err := info.Target(); err == nil || !strings.Contains(err.Error(), "no declaration named Missing") {
+		t.Fatalf("expected missing declaration error, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package gadget
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MismatchKind describes why a method required by an interface is not satisfied.
+type MismatchKind int
+
+const (
+	MissingMethod   MismatchKind = iota // The type has no method with the required name.
+	WrongSignature                      // The type has a method with the required name, but a different signature.
+	PointerReceiver                     // The method has a pointer receiver, and is only in the method set of the pointer type.
+)
+
+func (k MismatchKind) String() string {
+	switch k {
+	case MissingMethod:
+		return "MISSING"
+	case WrongSignature:
+		return "WRONG_SIGNATURE"
+	case PointerReceiver:
+		return "POINTER_RECEIVER"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// MethodMismatch is a method required by an interface that a type does not satisfy.
+type MethodMismatch struct {
+	Kind MismatchKind
+	Name string // The method name.
+	Want Func   // The signature required by the interface.
+	Have Func   // The signature of the method of the type. Empty for MissingMethod.
+}
+
+func (m MethodMismatch) String() string {
+	switch m.Kind {
+	case WrongSignature:
+		return fmt.Sprintf("wrong type for method %s: have %s%s, want %s%s", m.Name, m.Name, m.Have.toPrototype(), m.Name, m.Want.toPrototype())
+	case PointerReceiver:
+		return fmt.Sprintf("method %s has pointer receiver", m.Name)
+	default:
+		return fmt.Sprintf("missing method %s%s", m.Name, m.Want.toPrototype())
+	}
+}
+
+// MissingMethods compares the method set of the named type declared in the file against an interface.
+// If pointer is true, the method set of *T is used instead of that of T.
+// See Package.MissingMethods for details.
+func (f *File) MissingMethods(typeName string, pointer bool, iface Interface) ([]MethodMismatch, error) {
+	return newDeclIndex(f.Types, f.Funcs).missingMethods(typeName, pointer, iface)
+}
+
+// Implements returns true if the named type declared in the file satisfies the interface.
+// If pointer is true, *T is checked instead of T.
+func (f *File) Implements(typeName string, pointer bool, iface Interface) (bool, error) {
+	missing, err := f.MissingMethods(typeName, pointer, iface)
+	return len(missing) == 0, err
+}
+
+// MissingMethods compares the method set of the named type declared in the package against an interface.
+// If pointer is true, the method set of *T is used instead of that of T.
+// It returns the methods of the interface that the type does not satisfy, sorted by name.
+// An error is returned if the interface embeds interfaces that are not declared in the package,
+// or if methods are missing and the type embeds types that are not declared in the package.
+func (p *Package) MissingMethods(typeName string, pointer bool, iface Interface) ([]MethodMismatch, error) {
+	return newDeclIndex(p.Types, p.Funcs).missingMethods(typeName, pointer, iface)
+}
+
+// Implements returns true if the named type declared in the package satisfies the interface.
+// If pointer is true, *T is checked instead of T.
+func (p *Package) Implements(typeName string, pointer bool, iface Interface) (bool, error) {
+	missing, err := p.MissingMethods(typeName, pointer, iface)
+	return len(missing) == 0, err
+}
+
+func (idx *declIndex) missingMethods(typeName string, pointer bool, iface Interface) ([]MethodMismatch, error) {
+	want := idx.interfaceMethods(iface)
+	if len(want.Unresolved) > 0 {
+		return nil, fmt.Errorf("interface embeds undeclared types %s", typeList(want.Unresolved))
+	}
+	have, err := idx.methodSet(typeName, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get method set: %w", err)
+	}
+	var pointerHave *MethodSet
+	if !pointer {
+		pointerHave, err = idx.methodSet(typeName, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pointer method set: %w", err)
+		}
+	}
+	var missing []MethodMismatch
+	for _, method := range want.Methods {
+		if m, ok := have.Lookup(method.Name); ok {
+			if !sameSignature(m.Type, method.Type) {
+				missing = append(missing, MethodMismatch{Kind: WrongSignature, Name: method.Name, Want: method.Type, Have: m.Type})
+			}
+			continue
+		}
+		if pointerHave != nil {
+			if m, ok := pointerHave.Lookup(method.Name); ok {
+				missing = append(missing, MethodMismatch{Kind: PointerReceiver, Name: method.Name, Want: method.Type, Have: m.Type})
+				continue
+			}
+		}
+		missing = append(missing, MethodMismatch{Kind: MissingMethod, Name: method.Name, Want: method.Type})
+	}
+	if len(missing) > 0 && len(have.Unresolved) > 0 {
+		return missing, fmt.Errorf("type %s embeds undeclared types %s, which may provide missing methods", typeName, typeList(have.Unresolved))
+	}
+	return missing, nil
+}
+
+// sameSignature compares two function types, ignoring parameter and result names.
+func sameSignature(f, f2 Func) bool {
+	return Comparer{IgnoreParamNames: true, ByteRuneAliases: true}.Identical(f, f2)
+}
+
+func typeList(types []Type) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return strings.Join(names, ", ")
+}
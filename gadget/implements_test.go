@@ -0,0 +1,93 @@
+package gadget
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFile_MissingMethods(t *testing.T) {
+	src := `package test
+
+import "io"
+
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Close() error
+}
+
+type Memory struct{}
+
+func (m Memory) Get(k string) (v string, err error) { return }
+func (m *Memory) Set(k, v string) error { return nil }
+func (m Memory) Close() {}
+
+type Complete struct {
+	Memory
+}
+
+func (c *Complete) Close() error { return nil }
+
+type External struct {
+	io.Closer
+}
+`
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	store := f.GetTypes()["Store"].(Interface)
+
+	missing, err := f.MissingMethods("Memory", false, store)
+	if err != nil {
+		t.Fatalf("failed to check methods: %v", err)
+	}
+	expected := []MethodMismatch{
+		{
+			Kind: WrongSignature,
+			Name: "Close",
+			Want: Func{Results: []FuncResult{{Type: Error}}},
+			Have: Func{},
+		},
+		{
+			Kind: PointerReceiver,
+			Name: "Set",
+			Want: Func{Params: []FuncParam{{Name: "key", Type: String}, {Name: "value", Type: String}}, Results: []FuncResult{{Type: Error}}},
+			Have: Func{Params: []FuncParam{{Name: "k", Type: String}, {Name: "v", Type: String}}, Results: []FuncResult{{Type: Error}}},
+		},
+	}
+	if !reflect.DeepEqual(expected, missing) {
+		t.Logf("want: %#v", expected)
+		t.Logf(" got: %#v", missing)
+		t.Fatalf("invalid mismatches")
+	}
+	if s := missing[0].String(); s != "wrong type for method Close: have Close(), want Close() error" {
+		t.Fatalf("unexpected mismatch string: %s", s)
+	}
+
+	for _, test := range []struct {
+		name    string
+		pointer bool
+		want    bool
+	}{
+		{name: "Memory", pointer: true, want: false},
+		{name: "Complete", pointer: false, want: false},
+		{name: "Complete", pointer: true, want: true},
+	} {
+		ok, err := f.Implements(test.name, test.pointer, store)
+		if err != nil {
+			t.Fatalf("failed to check %s: %v", test.name, err)
+		}
+		if ok != test.want {
+			t.Errorf("expected Implements(%s, %t) to be %t", test.name, test.pointer, test.want)
+		}
+	}
+
+	if _, err := f.Implements("External", false, store); err == nil {
+		t.Fatalf("expected error for undeclared embedded type")
+	}
+	if _, err := f.Implements("Memory", false, Interface{Embeds: []Type{Selector{Left: "io", Right: "Reader"}}}); err == nil {
+		t.Fatalf("expected error for undeclared embedded interface")
+	}
+}
@@ -0,0 +1,711 @@
+package gadget
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"strings"
+)
+
+// The JSON encoding of Types is tagged with a kind, so that it can be decoded back into the right Type.
+// The kinds are the lowercase names of the Type variants, like "ident", "pointer" and "typeparam".
+// Fields that are empty are omitted.
+
+// MarshalType encodes t as JSON.
+// A nil Type is encoded as null.
+func MarshalType(t Type) ([]byte, error) {
+	jt, err := toJSONType(t)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jt)
+}
+
+// UnmarshalType decodes a Type encoded by MarshalType.
+func UnmarshalType(data []byte) (Type, error) {
+	var jt *jsonType
+	if err := json.Unmarshal(data, &jt); err != nil {
+		return nil, fmt.Errorf("failed to decode type: %w", err)
+	}
+	return jt.toType()
+}
+
+type jsonType struct {
+	Kind     string       `json:"kind"`
+	Name     string       `json:"name,omitempty"`
+	Package  string       `json:"package,omitempty"`
+	Elem     *jsonType    `json:"elem,omitempty"`
+	Key      *jsonType    `json:"key,omitempty"`
+	Value    *jsonType    `json:"value,omitempty"`
+	Size     *int         `json:"size,omitempty"`
+	Len      string       `json:"len,omitempty"`
+	Dir      string       `json:"dir,omitempty"`
+	Fields   []jsonField  `json:"fields,omitempty"`
+	Params   []jsonParam  `json:"params,omitempty"`
+	Results  []jsonParam  `json:"results,omitempty"`
+	Variadic bool         `json:"variadic,omitempty"`
+	Methods  []jsonMethod `json:"methods,omitempty"`
+	Embeds   []*jsonType  `json:"embeds,omitempty"`
+	Generic  *jsonType    `json:"generic,omitempty"`
+	Args     []*jsonType  `json:"args,omitempty"`
+	Terms    []jsonTerm   `json:"terms,omitempty"`
+}
+
+type jsonPosition struct {
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+	EndColumn int    `json:"end_column,omitempty"`
+}
+
+type jsonField struct {
+	Pos        *jsonPosition   `json:"pos,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Type       *jsonType       `json:"type"`
+	Tag        string          `json:"tag,omitempty"`
+	Doc        string          `json:"doc,omitempty"`
+	Directives []jsonDirective `json:"directives,omitempty"`
+}
+
+type jsonParam struct {
+	Name string    `json:"name,omitempty"`
+	Type *jsonType `json:"type"`
+}
+
+type jsonMethod struct {
+	Pos        *jsonPosition   `json:"pos,omitempty"`
+	Name       string          `json:"name"`
+	Type       *jsonType       `json:"type"`
+	Doc        string          `json:"doc,omitempty"`
+	Directives []jsonDirective `json:"directives,omitempty"`
+}
+
+type jsonTerm struct {
+	Tilde bool      `json:"tilde,omitempty"`
+	Type  *jsonType `json:"type"`
+}
+
+type jsonDirective struct {
+	Tool string `json:"tool"`
+	Name string `json:"name"`
+	Args string `json:"args,omitempty"`
+}
+
+type jsonTypeParam struct {
+	Name       string    `json:"name"`
+	Constraint *jsonType `json:"constraint,omitempty"`
+}
+
+func toJSONType(t Type) (*jsonType, error) {
+	if t == nil {
+		return nil, nil
+	}
+	var err error
+	elem := func(t Type) *jsonType {
+		if err != nil {
+			return nil
+		}
+		var jt *jsonType
+		jt, err = toJSONType(t)
+		return jt
+	}
+	var jt *jsonType
+	switch t := t.(type) {
+	case Ident:
+		jt = &jsonType{Kind: "ident", Name: string(t)}
+	case TypeParam:
+		jt = &jsonType{Kind: "typeparam", Name: string(t)}
+	case Selector:
+		jt = &jsonType{Kind: "selector", Package: string(t.Left), Name: string(t.Right)}
+	case Pointer:
+		jt = &jsonType{Kind: "pointer", Elem: elem(t.Elem)}
+	case Slice:
+		jt = &jsonType{Kind: "slice", Elem: elem(t.Elem)}
+	case Array:
+		size := t.Size
+		jt = &jsonType{Kind: "array", Elem: elem(t.Elem), Size: &size, Len: t.Len}
+	case Map:
+		jt = &jsonType{Kind: "map", Key: elem(t.Key), Value: elem(t.Value)}
+	case Chan:
+		jt = &jsonType{Kind: "chan", Dir: strings.ToLower(t.Dir.String()), Elem: elem(t.Elem)}
+	case Struct:
+		jt = &jsonType{Kind: "struct"}
+		for _, field := range t.Fields {
+			jt.Fields = append(jt.Fields, jsonField{
+				Pos:        toJSONPosition(field.Position),
+				Name:       field.Name,
+				Type:       elem(field.Type),
+				Tag:        field.Tag,
+				Doc:        field.Doc,
+				Directives: toJSONDirectives(field.Directives),
+			})
+		}
+	case Func:
+		jt = &jsonType{Kind: "func", Variadic: t.Variadic}
+		for _, param := range t.Params {
+			jt.Params = append(jt.Params, jsonParam{Name: param.Name, Type: elem(param.Type)})
+		}
+		for _, result := range t.Results {
+			jt.Results = append(jt.Results, jsonParam{Name: result.Name, Type: elem(result.Type)})
+		}
+	case Interface:
+		jt = &jsonType{Kind: "interface"}
+		for _, embed := range t.Embeds {
+			jt.Embeds = append(jt.Embeds, elem(embed))
+		}
+		for _, method := range t.Methods {
+			jt.Methods = append(jt.Methods, jsonMethod{
+				Pos:        toJSONPosition(method.Position),
+				Name:       method.Name,
+				Type:       elem(method.Type),
+				Doc:        method.Doc,
+				Directives: toJSONDirectives(method.Directives),
+			})
+		}
+	case Instance:
+		jt = &jsonType{Kind: "instance", Generic: elem(t.Type)}
+		for _, arg := range t.Args {
+			jt.Args = append(jt.Args, elem(arg))
+		}
+	case Union:
+		jt = &jsonType{Kind: "union"}
+		for _, term := range t.Terms {
+			jt.Terms = append(jt.Terms, jsonTerm{Tilde: term.Tilde, Type: elem(term.Type)})
+		}
+	default:
+		return nil, fmt.Errorf("unknown kind of type %T", t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jt, nil
+}
+
+func (jt *jsonType) toType() (Type, error) {
+	if jt == nil {
+		return nil, nil
+	}
+	var err error
+	elem := func(jt *jsonType) Type {
+		if err != nil {
+			return nil
+		}
+		var t Type
+		t, err = jt.toType()
+		return t
+	}
+	required := func(inner *jsonType, what string) Type {
+		if inner == nil && err == nil {
+			err = fmt.Errorf("missing %s in %s type", what, jt.Kind)
+		}
+		return elem(inner)
+	}
+	var t Type
+	switch jt.Kind {
+	case "ident":
+		t = Ident(jt.Name)
+	case "typeparam":
+		t = TypeParam(jt.Name)
+	case "selector":
+		t = Selector{Left: Ident(jt.Package), Right: Ident(jt.Name)}
+	case "pointer":
+		t = Pointer{Elem: required(jt.Elem, "elem")}
+	case "slice":
+		t = Slice{Elem: required(jt.Elem, "elem")}
+	case "array":
+		if jt.Size == nil {
+			return nil, fmt.Errorf("missing size in array type")
+		}
+		t = Array{Elem: required(jt.Elem, "elem"), Size: *jt.Size, Len: jt.Len}
+	case "map":
+		t = Map{Key: required(jt.Key, "key"), Value: required(jt.Value, "value")}
+	case "chan":
+		var dir ChanDir
+		switch jt.Dir {
+		case "both":
+			dir = BOTH
+		case "send":
+			dir = SEND
+		case "recv":
+			dir = RECV
+		default:
+			return nil, fmt.Errorf("unknown channel direction '%s'", jt.Dir)
+		}
+		t = Chan{Dir: dir, Elem: required(jt.Elem, "elem")}
+	case "struct":
+		var s Struct
+		for _, field := range jt.Fields {
+			s.Fields = append(s.Fields, StructField{
+				Position:   field.Pos.toPosition(),
+				Name:       field.Name,
+				Type:       required(field.Type, "field type"),
+				Tag:        field.Tag,
+				Doc:        field.Doc,
+				Directives: fromJSONDirectives(field.Directives),
+			})
+		}
+		t = s
+	case "func":
+		var f Func
+		f, err = jt.toFunc()
+		t = f
+	case "interface":
+		var i Interface
+		for _, embed := range jt.Embeds {
+			i.Embeds = append(i.Embeds, required(embed, "embed"))
+		}
+		for _, method := range jt.Methods {
+			var f Func
+			if method.Type == nil || method.Type.Kind != "func" {
+				return nil, fmt.Errorf("interface method %s is not a function type", method.Name)
+			}
+			f, err = method.Type.toFunc()
+			if err != nil {
+				return nil, err
+			}
+			i.Methods = append(i.Methods, InterfaceMethod{
+				Position:   method.Pos.toPosition(),
+				Name:       method.Name,
+				Type:       f,
+				Doc:        method.Doc,
+				Directives: fromJSONDirectives(method.Directives),
+			})
+		}
+		t = i
+	case "instance":
+		inst := Instance{Type: required(jt.Generic, "generic")}
+		for _, arg := range jt.Args {
+			inst.Args = append(inst.Args, required(arg, "type argument"))
+		}
+		t = inst
+	case "union":
+		var u Union
+		for _, term := range jt.Terms {
+			u.Terms = append(u.Terms, Term{Tilde: term.Tilde, Type: required(term.Type, "term type")})
+		}
+		t = u
+	default:
+		return nil, fmt.Errorf("unknown kind of type '%s'", jt.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (jt *jsonType) toFunc() (Func, error) {
+	f := Func{Variadic: jt.Variadic}
+	for _, param := range jt.Params {
+		t, err := param.Type.toType()
+		if err != nil {
+			return Func{}, err
+		}
+		if t == nil {
+			return Func{}, fmt.Errorf("missing parameter type in func type")
+		}
+		f.Params = append(f.Params, FuncParam{Name: param.Name, Type: t})
+	}
+	for _, result := range jt.Results {
+		t, err := result.Type.toType()
+		if err != nil {
+			return Func{}, err
+		}
+		if t == nil {
+			return Func{}, fmt.Errorf("missing result type in func type")
+		}
+		f.Results = append(f.Results, FuncResult{Name: result.Name, Type: t})
+	}
+	return f, nil
+}
+
+// toJSONPosition returns nil for the zero Position, so that it is omitted.
+func toJSONPosition(pos Position) *jsonPosition {
+	if pos == (Position{}) {
+		return nil
+	}
+	return &jsonPosition{Path: pos.Path, Line: pos.Line, Column: pos.Column, EndLine: pos.EndLine, EndColumn: pos.EndColumn}
+}
+
+func (pos *jsonPosition) toPosition() Position {
+	if pos == nil {
+		return Position{}
+	}
+	return Position{Path: pos.Path, Line: pos.Line, Column: pos.Column, EndLine: pos.EndLine, EndColumn: pos.EndColumn}
+}
+
+func toJSONDirectives(ds Directives) []jsonDirective {
+	var jds []jsonDirective
+	for _, d := range ds {
+		jds = append(jds, jsonDirective{Tool: d.Tool, Name: d.Name, Args: d.Args})
+	}
+	return jds
+}
+
+func fromJSONDirectives(jds []jsonDirective) Directives {
+	var ds Directives
+	for _, jd := range jds {
+		ds = append(ds, Directive{Tool: jd.Tool, Name: jd.Name, Args: jd.Args})
+	}
+	return ds
+}
+
+func toJSONTypeParams(params []TypeParamDecl) ([]jsonTypeParam, error) {
+	var jps []jsonTypeParam
+	for _, param := range params {
+		constraint, err := toJSONType(param.Constraint)
+		if err != nil {
+			return nil, err
+		}
+		jps = append(jps, jsonTypeParam{Name: param.Name, Constraint: constraint})
+	}
+	return jps, nil
+}
+
+func fromJSONTypeParams(jps []jsonTypeParam) ([]TypeParamDecl, error) {
+	var params []TypeParamDecl
+	for _, jp := range jps {
+		constraint, err := jp.Constraint.toType()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, TypeParamDecl{Name: jp.Name, Constraint: constraint})
+	}
+	return params, nil
+}
+
+type jsonFile struct {
+	Path       string       `json:"path"`
+	Package    string       `json:"package"`
+	Imports    []ImportDecl `json:"imports,omitempty"`
+	Types      []TypeDecl   `json:"types,omitempty"`
+	Funcs      []FuncDecl   `json:"funcs,omitempty"`
+	Consts     []ConstDecl  `json:"consts,omitempty"`
+	Vars       []VarDecl    `json:"vars,omitempty"`
+	Constraint string       `json:"constraint,omitempty"`
+	HasErrors  bool         `json:"has_errors,omitempty"`
+}
+
+// MarshalJSON encodes the file, tagging every Type with its kind.
+func (f File) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonFile(f))
+}
+
+// UnmarshalJSON decodes a file encoded by MarshalJSON.
+func (f *File) UnmarshalJSON(data []byte) error {
+	var jf jsonFile
+	if err := json.Unmarshal(data, &jf); err != nil {
+		return err
+	}
+	*f = File(jf)
+	return nil
+}
+
+type jsonImportDecl struct {
+	Pos  *jsonPosition `json:"pos,omitempty"`
+	Name string        `json:"name,omitempty"`
+	Path string        `json:"path"`
+}
+
+// MarshalJSON encodes the import declaration.
+func (d ImportDecl) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonImportDecl{
+		Pos:  toJSONPosition(d.Position),
+		Name: d.Name,
+		Path: d.Path,
+	})
+}
+
+// UnmarshalJSON decodes an import declaration encoded by MarshalJSON.
+func (d *ImportDecl) UnmarshalJSON(data []byte) error {
+	var jd jsonImportDecl
+	if err := json.Unmarshal(data, &jd); err != nil {
+		return err
+	}
+	*d = ImportDecl{
+		Position: jd.Pos.toPosition(),
+		Name:     jd.Name,
+		Path:     jd.Path,
+	}
+	return nil
+}
+
+type jsonTypeDecl struct {
+	Pos        *jsonPosition   `json:"pos,omitempty"`
+	Name       string          `json:"name"`
+	Doc        string          `json:"doc,omitempty"`
+	Directives []jsonDirective `json:"directives,omitempty"`
+	TypeParams []jsonTypeParam `json:"type_params,omitempty"`
+	Type       *jsonType       `json:"type,omitempty"`
+	Alias      *jsonType       `json:"alias,omitempty"`
+}
+
+// MarshalJSON encodes the type declaration, tagging every Type with its kind.
+func (d TypeDecl) MarshalJSON() ([]byte, error) {
+	jd := jsonTypeDecl{
+		Pos:        toJSONPosition(d.Position),
+		Name:       d.Name,
+		Doc:        d.Doc,
+		Directives: toJSONDirectives(d.Directives),
+	}
+	var err error
+	if jd.TypeParams, err = toJSONTypeParams(d.TypeParams); err != nil {
+		return nil, fmt.Errorf("failed to encode type parameters of type %s: %w", d.Name, err)
+	}
+	if jd.Type, err = toJSONType(d.Type); err != nil {
+		return nil, fmt.Errorf("failed to encode type %s: %w", d.Name, err)
+	}
+	if jd.Alias, err = toJSONType(d.Alias); err != nil {
+		return nil, fmt.Errorf("failed to encode alias %s: %w", d.Name, err)
+	}
+	return json.Marshal(jd)
+}
+
+// UnmarshalJSON decodes a type declaration encoded by MarshalJSON.
+func (d *TypeDecl) UnmarshalJSON(data []byte) error {
+	var jd jsonTypeDecl
+	if err := json.Unmarshal(data, &jd); err != nil {
+		return err
+	}
+	decl := TypeDecl{
+		Position:   jd.Pos.toPosition(),
+		Name:       jd.Name,
+		Doc:        jd.Doc,
+		Directives: fromJSONDirectives(jd.Directives),
+	}
+	var err error
+	if decl.TypeParams, err = fromJSONTypeParams(jd.TypeParams); err != nil {
+		return fmt.Errorf("failed to decode type parameters of type %s: %w", jd.Name, err)
+	}
+	if decl.Type, err = jd.Type.toType(); err != nil {
+		return fmt.Errorf("failed to decode type %s: %w", jd.Name, err)
+	}
+	if decl.Alias, err = jd.Alias.toType(); err != nil {
+		return fmt.Errorf("failed to decode alias %s: %w", jd.Name, err)
+	}
+	*d = decl
+	return nil
+}
+
+type jsonFuncDecl struct {
+	Pos         *jsonPosition   `json:"pos,omitempty"`
+	Name        string          `json:"name"`
+	Doc         string          `json:"doc,omitempty"`
+	Directives  []jsonDirective `json:"directives,omitempty"`
+	Recv        string          `json:"recv,omitempty"`
+	PointerRecv bool            `json:"pointer_recv,omitempty"`
+	TypeParams  []jsonTypeParam `json:"type_params,omitempty"`
+	Type        *jsonType       `json:"type"`
+}
+
+// MarshalJSON encodes the function declaration, tagging every Type with its kind.
+func (d FuncDecl) MarshalJSON() ([]byte, error) {
+	jd := jsonFuncDecl{
+		Pos:         toJSONPosition(d.Position),
+		Name:        d.Name,
+		Doc:         d.Doc,
+		Directives:  toJSONDirectives(d.Directives),
+		Recv:        d.Recv,
+		PointerRecv: d.PointerRecv,
+	}
+	var err error
+	if jd.TypeParams, err = toJSONTypeParams(d.TypeParams); err != nil {
+		return nil, fmt.Errorf("failed to encode type parameters of func %s: %w", d.Name, err)
+	}
+	if jd.Type, err = toJSONType(d.Type); err != nil {
+		return nil, fmt.Errorf("failed to encode func %s: %w", d.Name, err)
+	}
+	return json.Marshal(jd)
+}
+
+// UnmarshalJSON decodes a function declaration encoded by MarshalJSON.
+func (d *FuncDecl) UnmarshalJSON(data []byte) error {
+	var jd jsonFuncDecl
+	if err := json.Unmarshal(data, &jd); err != nil {
+		return err
+	}
+	if jd.Type == nil || jd.Type.Kind != "func" {
+		return fmt.Errorf("func %s does not have a func type", jd.Name)
+	}
+	typ, err := jd.Type.toFunc()
+	if err != nil {
+		return fmt.Errorf("failed to decode func %s: %w", jd.Name, err)
+	}
+	typeParams, err := fromJSONTypeParams(jd.TypeParams)
+	if err != nil {
+		return fmt.Errorf("failed to decode type parameters of func %s: %w", jd.Name, err)
+	}
+	*d = FuncDecl{
+		Position:    jd.Pos.toPosition(),
+		Name:        jd.Name,
+		Doc:         jd.Doc,
+		Directives:  fromJSONDirectives(jd.Directives),
+		Recv:        jd.Recv,
+		PointerRecv: jd.PointerRecv,
+		TypeParams:  typeParams,
+		Type:        typ,
+	}
+	return nil
+}
+
+// jsonValue encodes a constant.Value.
+// Values are encoded exactly: integers in decimal, floats as a decimal or a fraction, strings unquoted,
+// and complex numbers as their real and imaginary parts.
+type jsonValue struct {
+	Kind  string     `json:"kind"`
+	Value string     `json:"value,omitempty"`
+	Real  *jsonValue `json:"real,omitempty"`
+	Imag  *jsonValue `json:"imag,omitempty"`
+}
+
+func toJSONValue(v constant.Value) *jsonValue {
+	if v == nil {
+		return nil
+	}
+	switch v.Kind() {
+	case constant.Bool:
+		return &jsonValue{Kind: "bool", Value: v.ExactString()}
+	case constant.String:
+		return &jsonValue{Kind: "string", Value: constant.StringVal(v)}
+	case constant.Int:
+		return &jsonValue{Kind: "int", Value: v.ExactString()}
+	case constant.Float:
+		return &jsonValue{Kind: "float", Value: v.ExactString()}
+	case constant.Complex:
+		return &jsonValue{Kind: "complex", Real: toJSONValue(constant.Real(v)), Imag: toJSONValue(constant.Imag(v))}
+	}
+	return &jsonValue{Kind: "unknown"}
+}
+
+func (jv *jsonValue) toValue() (constant.Value, error) {
+	if jv == nil {
+		return nil, nil
+	}
+	switch jv.Kind {
+	case "bool":
+		return constant.MakeBool(jv.Value == "true"), nil
+	case "string":
+		return constant.MakeString(jv.Value), nil
+	case "int":
+		v := constant.MakeFromLiteral(jv.Value, token.INT, 0)
+		if v.Kind() != constant.Int {
+			return nil, fmt.Errorf("invalid integer constant '%s'", jv.Value)
+		}
+		return v, nil
+	case "float":
+		num, denom := jv.Value, "1"
+		if i := strings.Index(jv.Value, "/"); i >= 0 {
+			num, denom = jv.Value[:i], jv.Value[i+1:]
+		}
+		n := constant.MakeFromLiteral(num, token.FLOAT, 0)
+		d := constant.MakeFromLiteral(denom, token.FLOAT, 0)
+		if n.Kind() == constant.Unknown || d.Kind() == constant.Unknown || constant.Sign(d) == 0 {
+			return nil, fmt.Errorf("invalid float constant '%s'", jv.Value)
+		}
+		return constant.ToFloat(constant.BinaryOp(n, token.QUO, d)), nil
+	case "complex":
+		re, err := jv.Real.toValue()
+		if err != nil {
+			return nil, err
+		}
+		im, err := jv.Imag.toValue()
+		if err != nil {
+			return nil, err
+		}
+		if re == nil || im == nil {
+			return nil, fmt.Errorf("missing part of complex constant")
+		}
+		return constant.BinaryOp(re, token.ADD, constant.MakeImag(im)), nil
+	case "unknown":
+		return constant.MakeUnknown(), nil
+	}
+	return nil, fmt.Errorf("unknown kind of constant '%s'", jv.Kind)
+}
+
+type jsonConstDecl struct {
+	Pos   *jsonPosition `json:"pos,omitempty"`
+	Name  string        `json:"name"`
+	Type  *jsonType     `json:"type,omitempty"`
+	Value *jsonValue    `json:"value,omitempty"`
+	Iota  int           `json:"iota"`
+	Group int           `json:"group"`
+}
+
+// MarshalJSON encodes the constant declaration, including its exact value.
+func (d ConstDecl) MarshalJSON() ([]byte, error) {
+	typ, err := toJSONType(d.Type)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode type of const %s: %w", d.Name, err)
+	}
+	return json.Marshal(jsonConstDecl{
+		Pos:   toJSONPosition(d.Position),
+		Name:  d.Name,
+		Type:  typ,
+		Value: toJSONValue(d.Value),
+		Iota:  d.Iota,
+		Group: d.Group,
+	})
+}
+
+// UnmarshalJSON decodes a constant declaration encoded by MarshalJSON.
+func (d *ConstDecl) UnmarshalJSON(data []byte) error {
+	var jd jsonConstDecl
+	if err := json.Unmarshal(data, &jd); err != nil {
+		return err
+	}
+	typ, err := jd.Type.toType()
+	if err != nil {
+		return fmt.Errorf("failed to decode type of const %s: %w", jd.Name, err)
+	}
+	value, err := jd.Value.toValue()
+	if err != nil {
+		return fmt.Errorf("failed to decode value of const %s: %w", jd.Name, err)
+	}
+	*d = ConstDecl{
+		Position: jd.Pos.toPosition(),
+		Name:     jd.Name,
+		Type:     typ,
+		Value:    value,
+		Iota:     jd.Iota,
+		Group:    jd.Group,
+	}
+	return nil
+}
+
+type jsonVarDecl struct {
+	Pos  *jsonPosition `json:"pos,omitempty"`
+	Name string        `json:"name"`
+	Type *jsonType     `json:"type,omitempty"`
+}
+
+// MarshalJSON encodes the variable declaration, tagging its Type with its kind.
+func (d VarDecl) MarshalJSON() ([]byte, error) {
+	typ, err := toJSONType(d.Type)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode type of var %s: %w", d.Name, err)
+	}
+	return json.Marshal(jsonVarDecl{
+		Pos:  toJSONPosition(d.Position),
+		Name: d.Name,
+		Type: typ,
+	})
+}
+
+// UnmarshalJSON decodes a variable declaration encoded by MarshalJSON.
+func (d *VarDecl) UnmarshalJSON(data []byte) error {
+	var jd jsonVarDecl
+	if err := json.Unmarshal(data, &jd); err != nil {
+		return err
+	}
+	typ, err := jd.Type.toType()
+	if err != nil {
+		return fmt.Errorf("failed to decode type of var %s: %w", jd.Name, err)
+	}
+	*d = VarDecl{
+		Position: jd.Pos.toPosition(),
+		Name:     jd.Name,
+		Type:     typ,
+	}
+	return nil
+}
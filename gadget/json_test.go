@@ -0,0 +1,118 @@
+package gadget
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFile_JSON(t *testing.T) {
+	src := `package test
+
+import "io"
+
+// Number is a constraint.
+//
+//gadget:enum
+type Number interface {
+	~int | ~int64 | float64
+}
+
+type List[T any] struct {
+	Items []T ` + "`json:\"items\"`" + `
+	Next  *List[T]
+	Ch    <-chan [4]byte
+	Buf   [Size]byte
+}
+
+type Reader interface {
+	io.Reader
+	// Peek looks ahead.
+	Peek(n int) ([]byte, error)
+}
+
+type Alias = map[string]func(...interface{}) (n int, err error)
+
+func (l *List[T]) Push(v T) {}
+
+const (
+	Size           = 4
+	Third          = 1.0 / 3
+	Name           = "gadget"
+	Imag           = 2 + 3i
+	Yes            = true
+	Unknown        = unknown
+	Typed   Number = 1 << 70
+)
+
+var Out io.Writer
+`
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	example, err := NewFile(filepath.Join("example", "type.go"), nil)
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	for _, file := range []*File{f, example} {
+		b, err := json.Marshal(file)
+		if err != nil {
+			t.Fatalf("failed to encode %s: %v", file.Path, err)
+		}
+		var decoded File
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Fatalf("failed to decode %s: %v", file.Path, err)
+		}
+		if !reflect.DeepEqual(*file, decoded) {
+			t.Logf("want: %#v", *file)
+			t.Logf(" got: %#v", decoded)
+			t.Fatalf("invalid round trip of %s", file.Path)
+		}
+	}
+}
+
+func TestMarshalType(t *testing.T) {
+	typ := Map{Key: String, Value: Pointer{Elem: Selector{Left: "io", Right: "Reader"}}}
+	b, err := MarshalType(typ)
+	if err != nil {
+		t.Fatalf("failed to encode type: %v", err)
+	}
+	expected := `{"kind":"map","key":{"kind":"ident","name":"string"},"value":{"kind":"pointer","elem":{"kind":"selector","name":"Reader","package":"io"}}}`
+	var got, want interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	json.Unmarshal([]byte(expected), &want)
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected %s, got %s", expected, b)
+	}
+	decoded, err := UnmarshalType(b)
+	if err != nil {
+		t.Fatalf("failed to decode type: %v", err)
+	}
+	if !reflect.DeepEqual(typ, decoded) {
+		t.Fatalf("expected %#v, got %#v", typ, decoded)
+	}
+	if b, err := MarshalType(nil); err != nil || string(b) != "null" {
+		t.Fatalf("expected nil type to encode as null, got %s, %v", b, err)
+	}
+}
+
+func TestUnmarshalType_Invalid(t *testing.T) {
+	for _, data := range []string{
+		`{"kind":"unknown"}`,
+		`{"kind":"pointer"}`,
+		`{"kind":"array","elem":{"kind":"ident","name":"int"}}`,
+		`{"kind":"chan","dir":"sideways","elem":{"kind":"ident","name":"int"}}`,
+		`{"kind":"interface","methods":[{"name":"M","type":{"kind":"ident","name":"int"}}]}`,
+		`{"kind":"slice","elem":{"kind":"map","key":{"kind":"ident","name":"int"}}}`,
+		`[]`,
+	} {
+		if typ, err := UnmarshalType([]byte(data)); err == nil {
+			t.Errorf("expected error for %s, got %#v", data, typ)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package gadget
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Method is a method in the method set of a type.
+type Method struct {
+	Name        string
+	Type        Func
+	PointerRecv bool     // PointerRecv is true if the method is declared with a pointer receiver.
+	Path        []string // The embedded fields the method is promoted through, outermost first. Empty for methods declared on the type itself.
+}
+
+// MethodSet contains the methods of a type.
+type MethodSet struct {
+	Methods    []Method // The methods, sorted by name.
+	Unresolved []Type   // Embedded types that are not declared in the package, like io.Reader. Their methods are missing from Methods.
+}
+
+// Lookup finds the method with the given name.
+func (m *MethodSet) Lookup(name string) (Method, bool) {
+	for _, method := range m.Methods {
+		if method.Name == name {
+			return method, true
+		}
+	}
+	return Method{}, false
+}
+
+// LookupType finds the type declaration with the given name.
+func (f *File) LookupType(name string) (TypeDecl, bool) {
+	return lookupType(f.Types, name)
+}
+
+// LookupType finds the type declaration with the given name, across all files of the package.
+func (p *Package) LookupType(name string) (TypeDecl, bool) {
+	return lookupType(p.Types, name)
+}
+
+func lookupType(types []TypeDecl, name string) (TypeDecl, bool) {
+	for _, decl := range types {
+		if decl.Name == name {
+			return decl, true
+		}
+	}
+	return TypeDecl{}, false
+}
+
+// MethodSet computes the method set of the named type declared in the file.
+// If pointer is true, the method set of *T is returned instead of that of T.
+// Methods promoted through embedded struct fields and embedded interfaces are included.
+func (f *File) MethodSet(typeName string, pointer bool) (*MethodSet, error) {
+	return newDeclIndex(f.Types, f.Funcs).methodSet(typeName, pointer)
+}
+
+// MethodSet computes the method set of the named type declared in the package.
+// If pointer is true, the method set of *T is returned instead of that of T.
+// Methods promoted through embedded struct fields and embedded interfaces are included.
+func (p *Package) MethodSet(typeName string, pointer bool) (*MethodSet, error) {
+	return newDeclIndex(p.Types, p.Funcs).methodSet(typeName, pointer)
+}
+
+// InterfaceMethods flattens the methods of an interface, including those of embedded interfaces declared in the file.
+func (f *File) InterfaceMethods(iface Interface) *MethodSet {
+	return newDeclIndex(f.Types, f.Funcs).interfaceMethods(iface)
+}
+
+// InterfaceMethods flattens the methods of an interface, including those of embedded interfaces declared in the package.
+func (p *Package) InterfaceMethods(iface Interface) *MethodSet {
+	return newDeclIndex(p.Types, p.Funcs).interfaceMethods(iface)
+}
+
+// declIndex indexes type and method declarations by type name.
+type declIndex struct {
+	types   map[string]TypeDecl
+	methods map[string][]FuncDecl
+}
+
+func newDeclIndex(types []TypeDecl, funcs []FuncDecl) *declIndex {
+	idx := &declIndex{
+		types:   make(map[string]TypeDecl),
+		methods: make(map[string][]FuncDecl),
+	}
+	idx.types["error"] = TypeDecl{Name: "error", Type: errorInterface}
+	for _, decl := range types {
+		idx.types[decl.Name] = decl
+	}
+	for _, fun := range funcs {
+		if fun.Recv != "" {
+			idx.methods[fun.Recv] = append(idx.methods[fun.Recv], fun)
+		}
+	}
+	return idx
+}
+
+// resolve follows local aliases, returning the name of the defined type.
+// It returns false if the type is not declared locally.
+func (idx *declIndex) resolve(t Type) (string, bool) {
+	for i := 0; i < len(idx.types)+1; i++ {
+		if inst, ok := t.(Instance); ok {
+			t = inst.Type
+		}
+		id, ok := t.(Ident)
+		if !ok {
+			return "", false
+		}
+		decl, ok := idx.types[string(id)]
+		if !ok {
+			return "", false
+		}
+		if decl.Alias == nil {
+			return decl.Name, true
+		}
+		t = decl.Alias
+	}
+	return "", false
+}
+
+// underlying follows type definitions like type A B, returning the underlying type of the named type.
+func (idx *declIndex) underlying(name string) Type {
+	for i := 0; i < len(idx.types)+1; i++ {
+		t := idx.types[name].Type
+		next, ok := idx.resolve(t)
+		if !ok {
+			return t
+		}
+		name = next
+	}
+	return nil
+}
+
+// embeddedName returns the field name of an embedded field type, like T, *T, pkg.T or T[int].
+func embeddedName(t Type) string {
+	if ptr, ok := t.(Pointer); ok {
+		t = ptr.Elem
+	}
+	if inst, ok := t.(Instance); ok {
+		t = inst.Type
+	}
+	switch t := t.(type) {
+	case Ident:
+		return string(t)
+	case Selector:
+		return string(t.Right)
+	}
+	return t.String()
+}
+
+func (idx *declIndex) methodSet(typeName string, pointer bool) (*MethodSet, error) {
+	name, ok := idx.resolve(Ident(typeName))
+	if !ok {
+		return nil, fmt.Errorf("type '%s' is not declared", typeName)
+	}
+	if iface, ok := idx.underlying(name).(Interface); ok {
+		if pointer {
+			// A pointer to an interface has no methods.
+			return &MethodSet{}, nil
+		}
+		return idx.interfaceMethods(iface), nil
+	}
+
+	// Search the embedding tree breadth first; shallower names hide deeper ones,
+	// and names occurring more than once at the same depth are ambiguous and hide each other.
+	ms := &MethodSet{}
+	seen := make(map[string]bool)
+	visited := make(map[string]bool)
+	current := []embedEntry{{name: name, pointer: pointer}}
+	for len(current) > 0 {
+		var next []embedEntry
+		counts := make(map[string]int)
+		var candidates []Method
+		for _, e := range consolidate(current) {
+			if visited[e.name] {
+				continue
+			}
+			visited[e.name] = true
+			// Everything declared by a type embedded more than once at this depth is ambiguous.
+			weight := 1
+			if e.multi {
+				weight = 2
+			}
+			for _, fun := range idx.methods[e.name] {
+				counts[fun.Name] += weight
+				if fun.PointerRecv && !e.pointer {
+					continue
+				}
+				candidates = append(candidates, Method{
+					Name:        fun.Name,
+					Type:        fun.Type,
+					PointerRecv: fun.PointerRecv,
+					Path:        e.path,
+				})
+			}
+			switch u := idx.underlying(e.name).(type) {
+			case Interface:
+				flat := idx.interfaceMethods(u)
+				ms.Unresolved = append(ms.Unresolved, flat.Unresolved...)
+				for _, method := range flat.Methods {
+					counts[method.Name] += weight
+					method.Path = e.path
+					candidates = append(candidates, method)
+				}
+			case Struct:
+				for _, field := range u.Fields {
+					fieldName := field.FieldName()
+					counts[fieldName] += weight
+					if field.Name != "" {
+						continue
+					}
+					embedded := field.Type
+					embeddedPointer := e.pointer
+					if ptr, ok := embedded.(Pointer); ok {
+						embedded = ptr.Elem
+						embeddedPointer = true
+					}
+					embeddedType, ok := idx.resolve(embedded)
+					if !ok {
+						ms.Unresolved = append(ms.Unresolved, field.Type)
+						continue
+					}
+					path := append(append([]string(nil), e.path...), fieldName)
+					next = append(next, embedEntry{name: embeddedType, pointer: embeddedPointer, path: path})
+				}
+			}
+		}
+		for _, method := range candidates {
+			if counts[method.Name] == 1 && !seen[method.Name] {
+				ms.Methods = append(ms.Methods, method)
+			}
+		}
+		for name := range counts {
+			seen[name] = true
+		}
+		current = next
+	}
+	sort.Slice(ms.Methods, func(i, j int) bool {
+		return ms.Methods[i].Name < ms.Methods[j].Name
+	})
+	return ms, nil
+}
+
+// embedEntry is a type reached while searching the embedding tree of a method set.
+type embedEntry struct {
+	name    string
+	pointer bool
+	path    []string
+	multi   bool // The type is reached through more than one path at this depth.
+}
+
+// consolidate merges the entries at one depth that refer to the same type, keeping the first and marking it multi.
+func consolidate(entries []embedEntry) []embedEntry {
+	var merged []embedEntry
+	index := make(map[string]int)
+	for _, e := range entries {
+		if i, ok := index[e.name]; ok {
+			merged[i].multi = true
+			continue
+		}
+		index[e.name] = len(merged)
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+func (idx *declIndex) interfaceMethods(iface Interface) *MethodSet {
+	ms := &MethodSet{}
+	seen := make(map[string]bool)
+	visited := make(map[string]bool)
+	var flatten func(iface Interface)
+	flatten = func(iface Interface) {
+		for _, method := range iface.Methods {
+			if seen[method.Name] {
+				continue
+			}
+			seen[method.Name] = true
+			ms.Methods = append(ms.Methods, Method{
+				Name: method.Name,
+				Type: method.Type,
+			})
+		}
+		for _, embed := range iface.Embeds {
+			switch embed.(type) {
+			case Union:
+				// Type elements do not contribute methods.
+				continue
+			}
+			name, ok := idx.resolve(embed)
+			if !ok {
+				ms.Unresolved = append(ms.Unresolved, embed)
+				continue
+			}
+			if visited[name] {
+				continue
+			}
+			visited[name] = true
+			if embedded, ok := idx.underlying(name).(Interface); ok {
+				flatten(embedded)
+			}
+		}
+	}
+	flatten(iface)
+	sort.Slice(ms.Methods, func(i, j int) bool {
+		return ms.Methods[i].Name < ms.Methods[j].Name
+	})
+	return ms
+}
@@ -0,0 +1,137 @@
+package gadget
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const methodSetSource = `package test
+
+import "io"
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type ReadCloser interface {
+	Reader
+	Close() error
+}
+
+type ReadWriter interface {
+	ReadCloser
+	io.Writer
+	error
+}
+
+type Base struct{}
+
+func (b Base) Name() string { return "" }
+func (b *Base) SetName(name string) {}
+func (b Base) Shadowed() {}
+
+type Other struct{}
+
+func (o *Other) Ambiguous() {}
+
+type Another struct{}
+
+func (a Another) Ambiguous() {}
+
+type Derived struct {
+	Base
+	*Other
+	Another
+	io.Reader
+	Shadowed int
+}
+
+func (d Derived) Own() {}
+
+type Alias = Derived
+
+type Wrapped struct {
+	ReadCloser
+}
+
+type Top struct{}
+
+func (t Top) M() {}
+
+type Left struct{ Top }
+
+type Right struct{ Top }
+
+type Diamond struct {
+	Left
+	Right
+}
+`
+
+func methodNames(ms *MethodSet) []string {
+	var names []string
+	for _, method := range ms.Methods {
+		names = append(names, method.Name)
+	}
+	return names
+}
+
+func TestFile_MethodSet(t *testing.T) {
+	f, err := NewFile("test.go", strings.NewReader(methodSetSource))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	for _, test := range []struct {
+		name       string
+		pointer    bool
+		methods    []string
+		unresolved []Type
+	}{
+		{name: "Base", methods: []string{"Name", "Shadowed"}},
+		{name: "Base", pointer: true, methods: []string{"Name", "SetName", "Shadowed"}},
+		{name: "Derived", methods: []string{"Name", "Own"}, unresolved: []Type{Selector{Left: "io", Right: "Reader"}}},
+		{name: "Derived", pointer: true, methods: []string{"Name", "Own", "SetName"}, unresolved: []Type{Selector{Left: "io", Right: "Reader"}}},
+		{name: "Alias", methods: []string{"Name", "Own"}, unresolved: []Type{Selector{Left: "io", Right: "Reader"}}},
+		{name: "ReadWriter", methods: []string{"Close", "Error", "Read"}, unresolved: []Type{Selector{Left: "io", Right: "Writer"}}},
+		{name: "ReadWriter", pointer: true},
+		{name: "Wrapped", methods: []string{"Close", "Read"}},
+		{name: "Left", methods: []string{"M"}},
+		{name: "Diamond"},
+	} {
+		ms, err := f.MethodSet(test.name, test.pointer)
+		if err != nil {
+			t.Fatalf("failed to get method set of %s: %v", test.name, err)
+		}
+		if names := methodNames(ms); !reflect.DeepEqual(test.methods, names) {
+			t.Errorf("method set of %s (pointer %t): want %v, got %v", test.name, test.pointer, test.methods, names)
+		}
+		if !reflect.DeepEqual(test.unresolved, ms.Unresolved) {
+			t.Errorf("method set of %s (pointer %t): want unresolved %v, got %v", test.name, test.pointer, test.unresolved, ms.Unresolved)
+		}
+	}
+
+	ms, err := f.MethodSet("Derived", true)
+	if err != nil {
+		t.Fatalf("failed to get method set: %v", err)
+	}
+	setName, ok := ms.Lookup("SetName")
+	if !ok {
+		t.Fatalf("expected SetName in method set")
+	}
+	expected := Method{
+		Name:        "SetName",
+		Type:        Func{Params: []FuncParam{{Name: "name", Type: String}}},
+		PointerRecv: true,
+		Path:        []string{"Base"},
+	}
+	if !reflect.DeepEqual(expected, setName) {
+		t.Logf("want: %#v", expected)
+		t.Logf(" got: %#v", setName)
+		t.Fatalf("invalid method")
+	}
+
+	if _, err := f.MethodSet("Missing", false); err == nil {
+		t.Fatalf("expected error for undeclared type")
+	}
+}
@@ -0,0 +1,283 @@
+package gadget
+
+import (
+	"bytes"
+	"fmt"
+	"go/build/constraint"
+	"go/format"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output accumulates the source of a generated Go file.
+// Types written through Output are requalified for the generated file, and their imports are tracked.
+// Errors are sticky: the first error encountered is returned by Bytes and Write.
+type Output struct {
+	Path       string // The path the generated file is written to.
+	Package    string // The package name of the generated file.
+	Generator  string // The name of the generator, mentioned in the header. Defaults to "gadget".
+	Constraint string // A build constraint expression for the generated file, like "linux && amd64". Omitted if empty.
+
+	WriteFile func(path string, data []byte) error // Used by Write to write the generated file. Defaults to writing it to disk.
+
+	scope    *Scope
+	imports  map[string]string         // Import path to name.
+	names    map[string]string         // Name to import path.
+	reserved map[string]bool           // Package level identifiers that imports must not shadow.
+	dotPkgs  map[string]*types.Package // Dot imported packages, loaded to resolve identifiers.
+	body     bytes.Buffer
+	err      error
+}
+
+// NewOutput creates an Output for a file at path, in package pkg.
+// Selectors in types are resolved using scope, which should be the Scope of the file the types were read from.
+// If scope is nil, selectors are assumed to refer to packages imported by name with Import.
+// Imports are never named after the local types of scope.
+func NewOutput(path string, pkg string, scope *Scope) *Output {
+	o := &Output{
+		Path:     path,
+		Package:  pkg,
+		scope:    scope,
+		imports:  make(map[string]string),
+		names:    make(map[string]string),
+		reserved: make(map[string]bool),
+		dotPkgs:  make(map[string]*types.Package),
+	}
+	if scope != nil {
+		for name := range scope.Locals {
+			o.reserved[name] = true
+		}
+	}
+	return o
+}
+
+// Output creates an Output for the file <GOFILE>_<suffix>.go, next to the file that triggered generation.
+// Info.Open or Info.OpenPackage must be called first, so that types can be resolved.
+func (i *Info) Output(suffix string) (*Output, error) {
+	if i.file == nil {
+		return nil, fmt.Errorf("Info.Output called before Info.Open")
+	}
+	scope := i.file.Scope()
+	funcs, consts, vars := i.file.Funcs, i.file.Consts, i.file.Vars
+	if i.pkg != nil {
+		var err error
+		scope, err = i.pkg.Scope(i.file.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get scope: %w", err)
+		}
+		funcs, consts, vars = i.pkg.Funcs, i.pkg.Consts, i.pkg.Vars
+	}
+	base := strings.TrimSuffix(filepath.Base(i.File), ".go")
+	path := filepath.Join(filepath.Dir(i.File), base+"_"+suffix+".go")
+	o := NewOutput(path, i.Package, scope)
+	o.WriteFile = i.WriteFile
+	for _, decl := range funcs {
+		if decl.Recv == "" {
+			o.reserved[decl.Name] = true
+		}
+	}
+	for _, decl := range consts {
+		o.reserved[decl.Name] = true
+	}
+	for _, decl := range vars {
+		o.reserved[decl.Name] = true
+	}
+	return o, nil
+}
+
+// Import adds an import for the package at the given path, and returns the name to refer to it by.
+// The name is changed if it would clash with another import or with a package level identifier.
+func (o *Output) Import(path string) string {
+	if name, ok := o.imports[path]; ok {
+		return name
+	}
+	base := assumedPackageName(path)
+	name := base
+	for n := 2; o.names[name] != "" || o.reserved[name]; n++ {
+		name = base + strconv.Itoa(n)
+	}
+	o.imports[path] = name
+	o.names[name] = path
+	return name
+}
+
+// Type returns the source representation of t within the generated file,
+// importing the packages it refers to.
+func (o *Output) Type(t Type) string {
+	return o.requalify(t).String()
+}
+
+func (o *Output) requalify(t Type) Type {
+	return Rewrite(t, func(t Type) Type {
+		switch tt := t.(type) {
+		case Selector:
+			if o.scope == nil {
+				path, ok := o.names[string(tt.Left)]
+				if !ok {
+					o.fail(fmt.Errorf("no import for selector '%s'", tt))
+					return t
+				}
+				return Selector{Left: Ident(o.Import(path)), Right: tt.Right}
+			}
+			ref, err := o.scope.Lookup(tt)
+			if err != nil {
+				o.fail(err)
+				return t
+			}
+			return Selector{Left: Ident(o.Import(ref.Path)), Right: tt.Right}
+		case Ident:
+			if o.scope == nil || !token.IsExported(string(tt)) {
+				return t
+			}
+			ref, err := o.scope.Lookup(tt)
+			if err != nil || ref.Kind != DotImportRef {
+				return t
+			}
+			path, err := o.dotImportPath(string(tt))
+			if err != nil {
+				o.fail(err)
+				return t
+			}
+			if path == "" {
+				// Declared in a file of the package the scope does not see.
+				return t
+			}
+			return Selector{Left: Ident(o.Import(path)), Right: tt}
+		}
+		return t
+	})
+}
+
+// dotImportPath returns the path of the dot imported package declaring name, or an empty string if none does.
+func (o *Output) dotImportPath(name string) (string, error) {
+	var found string
+	for _, imp := range o.scope.Imports {
+		if imp.Name != "." {
+			continue
+		}
+		pkg, err := o.dotImport(imp.Path)
+		if err != nil {
+			return "", err
+		}
+		if pkg.Scope().Lookup(name) == nil {
+			continue
+		}
+		if found != "" {
+			return "", fmt.Errorf("ambiguous dot import for identifier '%s'", name)
+		}
+		found = imp.Path
+	}
+	return found, nil
+}
+
+// dotImport loads a dot imported package from source, as seen from the directory of the generated file.
+func (o *Output) dotImport(path string) (*types.Package, error) {
+	if pkg, ok := o.dotPkgs[path]; ok {
+		return pkg, nil
+	}
+	imp := importer.ForCompiler(token.NewFileSet(), "source", nil).(types.ImporterFrom)
+	pkg, err := imp.ImportFrom(path, filepath.Dir(o.Path), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dot import '%s': %w", path, err)
+	}
+	o.dotPkgs[path] = pkg
+	return pkg, nil
+}
+
+func (o *Output) fail(err error) {
+	if o.err == nil {
+		o.err = err
+	}
+}
+
+// Printf appends formatted source to the body of the generated file.
+func (o *Output) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(&o.body, format, args...)
+}
+
+// TypeDecl appends a type declaration.
+func (o *Output) TypeDecl(decl TypeDecl) {
+	if decl.Alias != nil {
+		o.Printf("type %s = %s\n\n", decl.Name, o.Type(decl.Alias))
+		return
+	}
+	o.Printf("type %s %s\n\n", decl.Name, o.Type(decl.Type))
+}
+
+// Func appends a function declaration with the given body.
+// recv is the receiver as written in source, like "v *T", and is empty for plain functions.
+func (o *Output) Func(recv string, name string, typ Func, body string) {
+	if recv != "" {
+		recv = "(" + recv + ") "
+	}
+	o.Printf("func %s%s%s {\n%s\n}\n\n", recv, name, o.requalify(typ).(Func).toPrototype(), body)
+}
+
+// Bytes returns the complete, gofmt'ed source of the generated file.
+func (o *Output) Bytes() ([]byte, error) {
+	if o.err != nil {
+		return nil, fmt.Errorf("failed to generate %s: %w", o.Path, o.err)
+	}
+	generator := o.Generator
+	if generator == "" {
+		generator = "gadget"
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by %s. DO NOT EDIT.\n\n", generator)
+	if o.Constraint != "" {
+		if _, err := constraint.Parse("//go:build " + o.Constraint); err != nil {
+			return nil, fmt.Errorf("failed to generate %s: invalid build constraint: %w", o.Path, err)
+		}
+		fmt.Fprintf(&buf, "//go:build %s\n\n", o.Constraint)
+	}
+	fmt.Fprintf(&buf, "package %s\n\n", o.Package)
+	if len(o.imports) > 0 {
+		paths := make([]string, 0, len(o.imports))
+		for importPath := range o.imports {
+			paths = append(paths, importPath)
+		}
+		sort.Strings(paths)
+		buf.WriteString("import (\n")
+		for _, importPath := range paths {
+			name := o.imports[importPath]
+			// Like goimports, only leave the name out when it is the last element of the path.
+			if name == path.Base(importPath) {
+				fmt.Fprintf(&buf, "\t%s\n", strconv.Quote(importPath))
+				continue
+			}
+			fmt.Fprintf(&buf, "\t%s %s\n", name, strconv.Quote(importPath))
+		}
+		buf.WriteString(")\n\n")
+	}
+	buf.Write(o.body.Bytes())
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format %s: %w", o.Path, err)
+	}
+	return formatted, nil
+}
+
+// Write writes the generated file to Path, using WriteFile if it is set.
+func (o *Output) Write() error {
+	b, err := o.Bytes()
+	if err != nil {
+		return err
+	}
+	writeFile := o.WriteFile
+	if writeFile == nil {
+		writeFile = func(path string, data []byte) error {
+			return ioutil.WriteFile(path, data, 0644)
+		}
+	}
+	if err := writeFile(o.Path, b); err != nil {
+		return fmt.Errorf("failed to write %s: %w", o.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package gadget
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutput(t *testing.T) {
+	src := `package test
+
+import (
+	"io"
+	stdjson "encoding/json"
+	. "strconv"
+	myjson "github.com/example/json"
+)
+
+type Thing struct {
+	R io.Reader
+	M stdjson.Marshaler
+	O myjson.Other
+	E NumError
+	L Local
+	S Sibling
+}
+
+type Local int
+`
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	o := NewOutput("test_gen.go", "test", f.Scope())
+	o.Generator = "gadget-test"
+	o.TypeDecl(TypeDecl{Name: "Copy", Type: f.GetTypes()["Thing"]})
+	o.Func("c *Copy", "Reader", Func{Results: []FuncResult{{Type: Selector{Left: "io", Right: "Reader"}}}}, "return c.R")
+	b, err := o.Bytes()
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	expected := `// Code generated by gadget-test. DO NOT EDIT.
+
+package test
+
+import (
+	"encoding/json"
+	json2 "github.com/example/json"
+	"io"
+	"strconv"
+)
+
+type Copy struct {
+	R io.Reader
+	M json.Marshaler
+	O json2.Other
+	E strconv.NumError
+	L Local
+	S Sibling
+}
+
+func (c *Copy) Reader() io.Reader {
+	return c.R
+}
+`
+	if string(b) != expected {
+		t.Logf("want: %s", expected)
+		t.Logf(" got: %s", b)
+		t.Fatalf("invalid output")
+	}
+}
+
+func TestOutput_Errors(t *testing.T) {
+	o := NewOutput("test_gen.go", "test", &Scope{})
+	o.Printf("var x %s\n", o.Type(Selector{Left: "io", Right: "Reader"}))
+	if _, err := o.Bytes(); err == nil {
+		t.Fatalf("expected error for unresolvable selector")
+	}
+
+	o = NewOutput("test_gen.go", "test", nil)
+	o.Printf("func {")
+	if _, err := o.Bytes(); err == nil {
+		t.Fatalf("expected error for invalid source")
+	}
+}
+
+func TestOutput_ImportNames(t *testing.T) {
+	o := NewOutput("test_gen.go", "test", nil)
+	for _, importPath := range []string{"io", "gopkg.in/yaml.v2", "github.com/example/go-cmp", "example.com/mod/v2"} {
+		o.Printf("var _ %s.Thing\n", o.Import(importPath))
+	}
+	b, err := o.Bytes()
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	expected := `import (
+	mod "example.com/mod/v2"
+	cmp "github.com/example/go-cmp"
+	yaml "gopkg.in/yaml.v2"
+	"io"
+)`
+	if !strings.Contains(string(b), expected) {
+		t.Logf("want: %s", expected)
+		t.Logf(" got: %s", b)
+		t.Fatalf("invalid imports")
+	}
+}
+
+func TestInfo_Output(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gadget")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "type.go")
+	if err := ioutil.WriteFile(path, []byte("package test\n\nimport \"io\"\n\ntype R io.Reader\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	info := &Info{Package: "test", File: path, Line: 4}
+	if _, err := info.Output("gen"); err == nil {
+		t.Fatalf("expected error before Open")
+	}
+	if _, err := info.Open(); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	o, err := info.Output("gen")
+	if err != nil {
+		t.Fatalf("failed to create output: %v", err)
+	}
+	if want := filepath.Join(dir, "type_gen.go"); o.Path != want {
+		t.Fatalf("expected output path %s, got %s", want, o.Path)
+	}
+	o.Printf("var _ %s\n", o.Type(Selector{Left: "io", Right: "Reader"}))
+	if err := o.Write(); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+	if _, err := NewFile(o.Path, nil); err != nil {
+		t.Fatalf("generated file does not parse: %v", err)
+	}
+}
+
+func TestInfo_Output_Reserved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gadget")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "type.go")
+	if err := ioutil.WriteFile(path, []byte("package test\n\ntype R int\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "other.go"), []byte("package test\n\nvar json = 1\n\nfunc errors() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	info := &Info{Package: "test", File: path, Line: 2}
+	if _, err := info.OpenPackage(); err != nil {
+		t.Fatalf("failed to open package: %v", err)
+	}
+	o, err := info.Output("gen")
+	if err != nil {
+		t.Fatalf("failed to create output: %v", err)
+	}
+	for path, want := range map[string]string{"encoding/json": "json2", "errors": "errors2", "io": "io"} {
+		if name := o.Import(path); name != want {
+			t.Errorf("import of %s: want %s, got %s", path, want, name)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package gadget
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Package contains all the information we have about a parsed Go package.
+// The declarations of all files are merged; the Position of each declaration tells which file it came from.
+type Package struct {
+	Dir        string       // The directory containing the package.
+	Name       string       // The package name.
+	ImportPath string       // The import path of the package, derived from the enclosing go.mod. Empty if there is none.
+	Files      []*File      // The parsed files, sorted by path.
+	Imports    []ImportDecl // The imports contained within all files of the package.
+	Types      []TypeDecl   // The Type declarations contained within all files of the package.
+	Funcs      []FuncDecl   // The Function declarations contained within all files of the package.
+	Consts     []ConstDecl  // The constant declarations contained within all files of the package.
+	Vars       []VarDecl    // The variable declarations contained within all files of the package.
+
+	fileSet *token.FileSet // The file set all files were parsed into.
+	syntax  []*ast.File    // The syntax trees of Files, kept for type checking.
+}
+
+// NewPackage parses every Go file in dir that belongs to the package called name.
+// Test files and files declaring a different package are skipped.
+// Build constraints are ignored; see NewPackageFor to select the files for a platform.
+func NewPackage(dir string, name string) (*Package, error) {
+	return newPackage(dir, name, nil, "")
+}
+
+// NewPackageFor parses the Go files in dir that belong to the package called name, like NewPackage,
+// but skips the files excluded by build constraints or file name suffixes in the given context.
+func NewPackageFor(dir string, name string, ctx BuildContext) (*Package, error) {
+	return newPackage(dir, name, &ctx, "")
+}
+
+// newPackage parses the Go files in dir that belong to the package called name, selected by ctx if it is not nil.
+// The file called include, if any, is parsed even if ctx excludes it; if it is a test file, the other test files are parsed as well.
+func newPackage(dir string, name string, ctx *BuildContext, include string) (*Package, error) {
+	tests := strings.HasSuffix(include, "_test.go")
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory '%s': %w", dir, err)
+	}
+	var paths []string
+	for _, info := range infos {
+		fileName := info.Name()
+		if info.IsDir() || !strings.HasSuffix(fileName, ".go") || (!tests && strings.HasSuffix(fileName, "_test.go")) {
+			continue
+		}
+		path := filepath.Join(dir, fileName)
+		pkgName, expr, err := readHeader(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package clause: %w", err)
+		}
+		if ctx != nil && fileName != include {
+			ok, err := ctx.Match(path, expr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		if pkgName != name {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no Go files for package '%s' in directory '%s'", name, dir)
+	}
+	sort.Strings(paths)
+
+	importPath, err := findImportPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	p := &Package{
+		Dir:        dir,
+		Name:       name,
+		ImportPath: importPath,
+		fileSet:    token.NewFileSet(),
+	}
+	consts := newConstEvaluator()
+	for _, path := range paths {
+		f, parsedFile, err := newFile(p.fileSet, path, nil, consts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse package file: %w", err)
+		}
+		p.Files = append(p.Files, f)
+		p.syntax = append(p.syntax, parsedFile)
+	}
+	// Constants may refer to constants in sibling files, so they are evaluated again at package scope.
+	for _, f := range p.Files {
+		setConstValues(f.Consts, consts)
+		p.Imports = append(p.Imports, f.Imports...)
+		p.Types = append(p.Types, f.Types...)
+		p.Funcs = append(p.Funcs, f.Funcs...)
+		p.Consts = append(p.Consts, f.Consts...)
+		p.Vars = append(p.Vars, f.Vars...)
+	}
+	return p, nil
+}
+
+// findImportPath returns the import path of the package in dir, using the module path in the nearest go.mod.
+// It returns an empty string if dir is not inside a module.
+func findImportPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory '%s': %w", dir, err)
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		data, err := ioutil.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			modulePath := modulePath(data)
+			if modulePath == "" {
+				return "", fmt.Errorf("no module path in '%s'", filepath.Join(root, "go.mod"))
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", fmt.Errorf("failed to resolve directory '%s': %w", dir, err)
+			}
+			return path.Join(modulePath, filepath.ToSlash(rel)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read go.mod: %w", err)
+		}
+		if filepath.Dir(root) == root {
+			return "", nil
+		}
+	}
+}
+
+// modulePath returns the module path declared by the module directive of a go.mod file, or an empty string.
+func modulePath(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if !strings.HasPrefix(line, "module") {
+			continue
+		}
+		fields := strings.Fields(line[len("module"):])
+		if len(fields) != 1 {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(fields[0]); err == nil {
+			return unquoted
+		}
+		return fields[0]
+	}
+	return ""
+}
+
+// File returns the parsed file with the given path, or nil if the package does not contain it.
+func (p *Package) File(path string) *File {
+	for _, f := range p.Files {
+		if f.Path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+// GetMethods fetches the methods belonging to the given type identifier, across all files of the package.
+func (p *Package) GetMethods(typeName string) map[string]Func {
+	return getMethods(p.Funcs, typeName)
+}
+
+// GetTypes fetches all non-alias types, across all files of the package.
+func (p *Package) GetTypes() map[string]Type {
+	return getTypes(p.Types)
+}
@@ -0,0 +1,105 @@
+package gadget
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewPackage(t *testing.T) {
+	p, err := NewPackage("example", "main")
+	if err != nil {
+		t.Fatalf("failed to create new package: %v", err)
+	}
+
+	var paths []string
+	for _, f := range p.Files {
+		paths = append(paths, f.Path)
+	}
+	expectedPaths := []string{filepath.Join("example", "main.go"), filepath.Join("example", "type.go")}
+	if !reflect.DeepEqual(expectedPaths, paths) {
+		t.Logf("want: %#v", expectedPaths)
+		t.Logf(" got: %#v", paths)
+		t.Fatalf("invalid files")
+	}
+
+	if len(p.Imports) != 6 {
+		t.Fatalf("expected 6 imports across files, got %d", len(p.Imports))
+	}
+	if p.Imports[0].Path != "fmt" || p.Imports[0].Position != (Position{Path: expectedPaths[0], Line: 4, Column: 2, EndLine: 4, EndColumn: 7}) {
+		t.Fatalf("unexpected first import: %#v", p.Imports[0])
+	}
+
+	var funcs []string
+	for _, fun := range p.Funcs {
+		funcs = append(funcs, fun.Position.String()+" "+fun.Name)
+	}
+	expectedFuncs := []string{
+		filepath.Join("example", "main.go") + ":10:1 main",
+		filepath.Join("example", "main.go") + ":17:1 run",
+		filepath.Join("example", "type.go") + ":16:1 String",
+		filepath.Join("example", "type.go") + ":30:1 hello",
+	}
+	if !reflect.DeepEqual(expectedFuncs, funcs) {
+		t.Logf("want: %#v", expectedFuncs)
+		t.Logf(" got: %#v", funcs)
+		t.Fatalf("invalid funcs")
+	}
+
+	expectedMethods := map[string]Func{
+		"String": {Results: []FuncResult{{Type: String}}},
+	}
+	methods := p.GetMethods("ExaType")
+	if !reflect.DeepEqual(expectedMethods, methods) {
+		t.Logf("want: %#v", expectedMethods)
+		t.Logf(" got: %#v", methods)
+		t.Fatalf("invalid methods")
+	}
+
+	types := p.GetTypes()
+	if len(types) != 2 || types["Smoo"] != Int {
+		t.Fatalf("invalid types: %#v", types)
+	}
+
+	if p.File(expectedPaths[1]) == nil {
+		t.Fatalf("expected to find file %s", expectedPaths[1])
+	}
+}
+
+func TestNewPackage_WrongName(t *testing.T) {
+	if _, err := NewPackage("example", "example"); err == nil {
+		t.Fatalf("expected error for package without files")
+	}
+}
+
+func TestNewPackage_Consts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gadget")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.go": "package test\n\nconst Answer = Base + 1\n\nvar buf [Answer]byte\n",
+		"b.go": "package test\n\nconst Base uint8 = 41\n",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	p, err := NewPackage(dir, "test")
+	if err != nil {
+		t.Fatalf("failed to parse package: %v", err)
+	}
+	if c := p.Consts[0]; c.Name != "Answer" || c.Value.ExactString() != "42" {
+		t.Fatalf("expected Answer to be 42, got %#v", c)
+	}
+	if c := p.Files[0].Consts[0]; c.Value.ExactString() != "42" {
+		t.Fatalf("expected Answer to be 42 in its file, got %#v", c)
+	}
+	if n, err := p.ArrayLen(p.Vars[0].Type.(Array)); err != nil || n != 42 {
+		t.Fatalf("expected array length 42, got %d, %v", n, err)
+	}
+}
@@ -3,7 +3,6 @@ package gadget
 import (
 	"fmt"
 	"go/parser"
-	"reflect"
 )
 
 // ParseType takes a string containing a Go type definition,
@@ -29,7 +28,8 @@ func TypeIs(t Type, s string) bool {
 	return SameType(t, t2)
 }
 
-// SameType will return true if the given types are the same.
+// SameType will return true if the given types are written the same.
+// Use a Comparer for more lenient comparisons.
 func SameType(t, t2 Type) bool {
-	return reflect.DeepEqual(t, t2)
+	return Comparer{}.Identical(t, t2)
 }
@@ -89,6 +89,56 @@ func TestParseType(t *testing.T) {
 				{Name: "Set", Type: Func{Params: []FuncParam{{Type: Int}}}},
 			}},
 		},
+		{
+			s: "[2*8]byte",
+			t: Array{Size: 16, Elem: Byte},
+		},
+		{
+			s: "[N]byte",
+			t: Array{Size: -1, Len: "N", Elem: Byte},
+		},
+		{
+			s: "[...]int",
+			t: Array{Size: -1, Len: "...", Elem: Int},
+		},
+		{
+			s: "(*int)",
+			t: Pointer{Elem: Int},
+		},
+		{
+			s: "chan (<-chan int)",
+			t: Chan{Dir: BOTH, Elem: Chan{Dir: RECV, Elem: Int}},
+		},
+		{
+			s: "func(format string, args ...interface{})",
+			t: Func{
+				Params: []FuncParam{
+					{Name: "format", Type: String},
+					{Name: "args", Type: Slice{Elem: Interface{}}},
+				},
+				Variadic: true,
+			},
+		},
+		{
+			s: "List[int]",
+			t: Instance{Type: Ident("List"), Args: []Type{Int}},
+		},
+		{
+			s: "sync.Map[string, []int]",
+			t: Instance{Type: Selector{Left: "sync", Right: "Map"}, Args: []Type{String, Slice{Elem: Int}}},
+		},
+		{
+			s: "interface{~int | string; io.Reader; String() string}",
+			t: Interface{
+				Embeds: []Type{
+					Union{Terms: []Term{{Tilde: true, Type: Int}, {Type: String}}},
+					Selector{Left: "io", Right: "Reader"},
+				},
+				Methods: []InterfaceMethod{
+					{Name: "String", Type: Func{Results: []FuncResult{{Type: String}}}},
+				},
+			},
+		},
 	} {
 		t.Run(test.s, func(t *testing.T) {
 			typ, err := ParseType(test.s)
@@ -103,3 +153,32 @@ func TestParseType(t *testing.T) {
 		})
 	}
 }
+
+func TestParseType_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"func(a ...int, b int)",
+		"[]...int",
+		"1 + 2",
+	} {
+		if _, err := ParseType(s); err == nil {
+			t.Errorf("expected error parsing %s", s)
+		}
+	}
+}
+
+func TestTypeString(t *testing.T) {
+	for _, s := range []string{
+		"func(string, ...int) error",
+		"[N * 2]byte",
+		"[...]string",
+		"map[string][4]int",
+	} {
+		typ, err := ParseType(s)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", s, err)
+		}
+		if typ.String() != s {
+			t.Errorf("expected %s, got %s", s, typ.String())
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package gadget
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"strings"
+)
+
+// FromReflect converts a runtime type to a Type.
+// Named types become an Ident if they are predeclared, like int or error,
+// and a Selector using the last element of their package path otherwise, like json.Decoder.
+// Instantiated generic types become an Instance, like List[int].
+// Unnamed types are converted structurally, keeping struct field names and tags.
+//
+// Types declared in the package being generated for are also converted to a Selector, like main.Config,
+// since reflect does not know where the generated code lives; replace them with an Ident where needed.
+//
+// Some information is not available at runtime: byte and rune become uint8 and int32,
+// interfaces lose their embeds in favor of the flattened method set, and array lengths are always numbers.
+func FromReflect(t reflect.Type) Type {
+	if t == nil {
+		return nil
+	}
+	if name := t.Name(); name != "" {
+		if t.PkgPath() == "" {
+			return Ident(name)
+		}
+		pkg := Ident(assumedPackageName(t.PkgPath()))
+		if i := strings.Index(name, "["); i >= 0 && strings.HasSuffix(name, "]") {
+			return instanceFromReflect(pkg, name[:i], name[i:])
+		}
+		return Selector{Left: pkg, Right: Ident(name)}
+	}
+	return FromReflectUnderlying(t)
+}
+
+// instanceFromReflect converts the name of an instantiated generic type, split into its name and type arguments like [int,*encoding/json.Decoder].
+// Runtime type arguments are qualified by their full package path, which is shortened the same way FromReflect does.
+func instanceFromReflect(pkg Ident, name string, args string) Type {
+	generic := Selector{Left: pkg, Right: Ident(name)}
+	parsed, err := ParseType(name + shortenPackagePaths(args))
+	if err != nil {
+		return Instance{Type: generic}
+	}
+	inst, ok := parsed.(Instance)
+	if !ok {
+		return Instance{Type: generic}
+	}
+	inst.Type = generic
+	return inst
+}
+
+// shortenPackagePaths replaces the package paths qualifying names in a runtime type string, like encoding/json.Decoder,
+// with the assumed package name, like json.Decoder. Quoted struct tags are left alone.
+func shortenPackagePaths(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(s) {
+				end++
+			}
+			b.WriteString(s[i:end])
+			i = end
+		case isPathByte(c):
+			end := i
+			for end < len(s) && isPathByte(s[end]) {
+				end++
+			}
+			word := s[i:end]
+			if dot := strings.LastIndex(word, "."); dot > 0 {
+				word = assumedPackageName(word[:dot]) + word[dot:]
+			}
+			b.WriteString(word)
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isPathByte(c byte) bool {
+	return c == '_' || c == '.' || c == '/' || c == '-' || c == '~' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c >= 0x80
+}
+
+// FromReflectUnderlying converts the underlying type of a runtime type to a Type.
+// Unlike FromReflect, the structure of a named type is converted, rather than its name.
+// Types contained within it are converted using FromReflect.
+func FromReflectUnderlying(t reflect.Type) Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return Pointer{Elem: FromReflect(t.Elem())}
+	case reflect.Slice:
+		return Slice{Elem: FromReflect(t.Elem())}
+	case reflect.Array:
+		return Array{Elem: FromReflect(t.Elem()), Size: t.Len()}
+	case reflect.Map:
+		return Map{Key: FromReflect(t.Key()), Value: FromReflect(t.Elem())}
+	case reflect.Chan:
+		dir := BOTH
+		switch t.ChanDir() {
+		case reflect.SendDir:
+			dir = SEND
+		case reflect.RecvDir:
+			dir = RECV
+		}
+		return Chan{Dir: dir, Elem: FromReflect(t.Elem())}
+	case reflect.Struct:
+		var s Struct
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := field.Name
+			if field.Anonymous {
+				name = ""
+			}
+			s.Fields = append(s.Fields, StructField{
+				Name: name,
+				Type: FromReflect(field.Type),
+				Tag:  string(field.Tag),
+			})
+		}
+		return s
+	case reflect.Func:
+		return funcFromReflect(t)
+	case reflect.Interface:
+		var iface Interface
+		for i := 0; i < t.NumMethod(); i++ {
+			method := t.Method(i)
+			iface.Methods = append(iface.Methods, InterfaceMethod{
+				Name: method.Name,
+				Type: funcFromReflect(method.Type),
+			})
+		}
+		return iface
+	case reflect.UnsafePointer:
+		return Selector{Left: "unsafe", Right: "Pointer"}
+	}
+	// The kinds of predeclared types are named after them.
+	return Ident(t.Kind().String())
+}
+
+func funcFromReflect(t reflect.Type) Func {
+	f := Func{Variadic: t.IsVariadic()}
+	for i := 0; i < t.NumIn(); i++ {
+		f.Params = append(f.Params, FuncParam{Type: FromReflect(t.In(i))})
+	}
+	for i := 0; i < t.NumOut(); i++ {
+		f.Results = append(f.Results, FuncResult{Type: FromReflect(t.Out(i))})
+	}
+	return f
+}
+
+var reflectBuiltins = map[Ident]reflect.Type{
+	Bool:       reflect.TypeOf(false),
+	Byte:       reflect.TypeOf(byte(0)),
+	Complex64:  reflect.TypeOf(complex64(0)),
+	Complex128: reflect.TypeOf(complex128(0)),
+	Error:      reflect.TypeOf((*error)(nil)).Elem(),
+	Float32:    reflect.TypeOf(float32(0)),
+	Float64:    reflect.TypeOf(float64(0)),
+	Int:        reflect.TypeOf(int(0)),
+	Int8:       reflect.TypeOf(int8(0)),
+	Int16:      reflect.TypeOf(int16(0)),
+	Int32:      reflect.TypeOf(int32(0)),
+	Int64:      reflect.TypeOf(int64(0)),
+	Rune:       reflect.TypeOf(rune(0)),
+	String:     reflect.TypeOf(""),
+	Uint:       reflect.TypeOf(uint(0)),
+	Uint8:      reflect.TypeOf(uint8(0)),
+	Uint16:     reflect.TypeOf(uint16(0)),
+	Uint32:     reflect.TypeOf(uint32(0)),
+	Uint64:     reflect.TypeOf(uint64(0)),
+	Uintptr:    reflect.TypeOf(uintptr(0)),
+	"any":      reflect.TypeOf((*interface{})(nil)).Elem(),
+}
+
+// ToReflect converts a Type to a runtime type.
+// Only predeclared types, and types composed of them, can be converted:
+// declared types, selectors, type parameters and generic types are unknown at runtime.
+// Interfaces can only be converted if they are empty, and structs only if their fields are exported and not embedded.
+func ToReflect(t Type) (reflect.Type, error) {
+	switch t := t.(type) {
+	case Ident:
+		rt, ok := reflectBuiltins[t]
+		if !ok {
+			return nil, fmt.Errorf("type %s is not a predeclared type", t)
+		}
+		return rt, nil
+	case Pointer:
+		elem, err := ToReflect(t.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PtrTo(elem), nil
+	case Slice:
+		elem, err := ToReflect(t.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+	case Array:
+		if t.Len != "" {
+			return nil, fmt.Errorf("length of array %s is not a number", t)
+		}
+		elem, err := ToReflect(t.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(t.Size, elem), nil
+	case Map:
+		key, err := ToReflect(t.Key)
+		if err != nil {
+			return nil, err
+		}
+		if !key.Comparable() {
+			return nil, fmt.Errorf("invalid map key type %s", t.Key)
+		}
+		value, err := ToReflect(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(key, value), nil
+	case Chan:
+		elem, err := ToReflect(t.Elem)
+		if err != nil {
+			return nil, err
+		}
+		dir := reflect.BothDir
+		switch t.Dir {
+		case SEND:
+			dir = reflect.SendDir
+		case RECV:
+			dir = reflect.RecvDir
+		}
+		return reflect.ChanOf(dir, elem), nil
+	case Struct:
+		var fields []reflect.StructField
+		for _, field := range t.Fields {
+			if field.Name == "" {
+				return nil, fmt.Errorf("embedded field %s is not supported", field.Type)
+			}
+			if field.Name == "_" {
+				return nil, fmt.Errorf("blank field is not supported")
+			}
+			if !token.IsExported(field.Name) {
+				return nil, fmt.Errorf("unexported field %s is not supported", field.Name)
+			}
+			typ, err := ToReflect(field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert field %s: %w", field.Name, err)
+			}
+			fields = append(fields, reflect.StructField{
+				Name: field.Name,
+				Type: typ,
+				Tag:  reflect.StructTag(field.Tag),
+			})
+		}
+		return reflect.StructOf(fields), nil
+	case Func:
+		var in, out []reflect.Type
+		for _, param := range t.Params {
+			typ, err := ToReflect(param.Type)
+			if err != nil {
+				return nil, err
+			}
+			in = append(in, typ)
+		}
+		for _, result := range t.Results {
+			typ, err := ToReflect(result.Type)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, typ)
+		}
+		return reflect.FuncOf(in, out, t.Variadic), nil
+	case Interface:
+		if len(t.Methods) > 0 || len(t.Embeds) > 0 {
+			return nil, fmt.Errorf("non-empty interface %s can not be created at runtime", t)
+		}
+		return reflectBuiltins["any"], nil
+	case nil:
+		return nil, fmt.Errorf("nil type")
+	}
+	return nil, fmt.Errorf("type %s can not be converted to a runtime type", t)
+}
@@ -0,0 +1,113 @@
+package gadget
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type reflectPair[K comparable, V any] struct{}
+
+func TestFromReflect(t *testing.T) {
+	type local struct {
+		R    io.Reader `json:"r"`
+		D    *json.Decoder
+		M    map[string][]*int
+		A    [4]uint16
+		C    <-chan error
+		F    func(string, ...interface{}) (int, error)
+		I    interface{ Close() error }
+		Next *local
+	}
+	src := "package test\n\ntype T struct {\n" +
+		"\tR io.Reader `json:\"r\"`\n" +
+		"\tD *json.Decoder\n" +
+		"\tM map[string][]*int\n" +
+		"\tA [4]uint16\n" +
+		"\tC <-chan error\n" +
+		"\tF func(string, ...interface{}) (int, error)\n" +
+		"\tI interface{ Close() error }\n" +
+		"\tNext *gadget.local\n" +
+		"}\n"
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	parsed := f.GetTypes()["T"]
+	converted := FromReflectUnderlying(reflect.TypeOf(local{}))
+	if !SameType(converted, parsed) {
+		t.Logf("want: %s", parsed)
+		t.Logf(" got: %s", converted)
+		t.Fatalf("invalid conversion")
+	}
+	if typ := FromReflect(reflect.TypeOf(local{})); typ != (Selector{Left: "gadget", Right: "local"}) {
+		t.Fatalf("expected gadget.local, got %s", typ)
+	}
+	if typ := FromReflectUnderlying(reflect.TypeOf(Ident(""))); typ != String {
+		t.Fatalf("expected string, got %s", typ)
+	}
+	if typ := FromReflect(reflect.TypeOf([]byte(nil))); typ != (Slice{Elem: Uint8}) {
+		t.Fatalf("expected []uint8, got %s", typ)
+	}
+	expected := "gadget.reflectPair[string, map[string]*json.Decoder]"
+	if typ := FromReflect(reflect.TypeOf(reflectPair[string, map[string]*json.Decoder]{})); !TypeIs(typ, expected) {
+		t.Fatalf("expected %s, got %s", expected, typ)
+	}
+	expected = "gadget.reflectPair[int, struct{ A int `json:\"a.b\"` }]"
+	if typ := FromReflect(reflect.TypeOf(reflectPair[int, struct {
+		A int `json:"a.b"`
+	}]{})); !TypeIs(typ, expected) {
+		t.Fatalf("expected %s, got %s", expected, typ)
+	}
+}
+
+func TestToReflect(t *testing.T) {
+	for _, test := range []struct {
+		src      string
+		expected reflect.Type
+	}{
+		{"map[string][]*int", reflect.TypeOf(map[string][]*int(nil))},
+		{"[3]chan<- error", reflect.TypeOf([3]chan<- error{})},
+		{"func(string, ...interface{}) (byte, error)", reflect.TypeOf(func(string, ...interface{}) (byte, error) { return 0, nil })},
+		{"struct{A int `json:\"a\"`; B []string}", reflect.TypeOf(struct {
+			A int `json:"a"`
+			B []string
+		}{})},
+	} {
+		typ, err := ParseType(test.src)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", test.src, err)
+		}
+		got, err := ToReflect(typ)
+		if err != nil {
+			t.Fatalf("failed to convert %s: %v", test.src, err)
+		}
+		if got != test.expected {
+			t.Fatalf("expected %v, got %v", test.expected, got)
+		}
+		if back := FromReflect(got); !(Comparer{ByteRuneAliases: true}).Identical(back, typ) {
+			t.Fatalf("expected %s to round trip, got %s", typ, back)
+		}
+	}
+	for _, src := range []string{
+		"io.Reader",
+		"Local",
+		"map[[]int]string",
+		"struct{ a int }",
+		"struct{ _ int }",
+		"struct{ é int }",
+		"struct{ io.Reader }",
+		"interface{ Close() error }",
+		"[N]int",
+	} {
+		typ, err := ParseType(src)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", src, err)
+		}
+		if got, err := ToReflect(typ); err == nil {
+			t.Errorf("expected error converting %s, got %v", src, got)
+		}
+	}
+}
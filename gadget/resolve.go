@@ -0,0 +1,183 @@
+package gadget
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// RefKind describes where an identifier used in a Type was declared.
+type RefKind int
+
+const (
+	LocalRef     RefKind = iota // Declared in the package itself.
+	BuiltinRef                  // A predeclared identifier, like int or error.
+	DotImportRef                // Made visible by a dot import.
+	ImportRef                   // Qualified by an import, like io.Reader.
+)
+
+func (k RefKind) String() string {
+	switch k {
+	case LocalRef:
+		return "LOCAL"
+	case BuiltinRef:
+		return "BUILTIN"
+	case DotImportRef:
+		return "DOTIMPORT"
+	case ImportRef:
+		return "IMPORT"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Reference is a resolved Ident or Selector.
+type Reference struct {
+	Type Type    // The Ident or Selector that was resolved.
+	Kind RefKind // Where the identifier was declared.
+	Path string  // The import path of the package declaring the identifier. Empty for LocalRef and BuiltinRef.
+	Name string  // The name of the identifier within its package.
+}
+
+func (r Reference) String() string {
+	if r.Path == "" {
+		return r.Name
+	}
+	return strconv.Quote(r.Path) + "." + r.Name
+}
+
+var builtinTypes = map[string]bool{
+	"bool": true, "byte": true, "complex64": true, "complex128": true, "error": true,
+	"float32": true, "float64": true, "int": true, "int8": true, "int16": true,
+	"int32": true, "int64": true, "rune": true, "string": true, "uint": true,
+	"uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"any": true, "comparable": true,
+}
+
+// Scope resolves the identifiers used in types as seen from a single Go file.
+type Scope struct {
+	Imports []ImportDecl    // The imports of the file.
+	Locals  map[string]bool // The type names declared in the package.
+}
+
+// Scope returns the Scope of the file.
+// Only types declared in the file itself are considered local;
+// use Package.Scope to see the types declared in sibling files.
+func (f *File) Scope() *Scope {
+	return newScope(f.Imports, f.Types)
+}
+
+// Scope returns the Scope of the package file with the given path.
+func (p *Package) Scope(path string) (*Scope, error) {
+	f := p.File(path)
+	if f == nil {
+		return nil, fmt.Errorf("file '%s' is not part of package '%s'", path, p.Name)
+	}
+	return newScope(f.Imports, p.Types), nil
+}
+
+func newScope(imports []ImportDecl, types []TypeDecl) *Scope {
+	s := &Scope{
+		Imports: imports,
+		Locals:  make(map[string]bool),
+	}
+	for _, decl := range types {
+		s.Locals[decl.Name] = true
+	}
+	return s
+}
+
+// Lookup resolves a single Ident or Selector.
+// Identifiers that are neither local nor builtin are assumed to come from a dot import;
+// if there is exactly one, its path is filled in.
+func (s *Scope) Lookup(t Type) (Reference, error) {
+	switch t := t.(type) {
+	case Ident:
+		name := string(t)
+		if s.Locals[name] {
+			return Reference{Type: t, Kind: LocalRef, Name: name}, nil
+		}
+		if builtinTypes[name] {
+			return Reference{Type: t, Kind: BuiltinRef, Name: name}, nil
+		}
+		var dotImports []ImportDecl
+		for _, imp := range s.Imports {
+			if imp.Name == "." {
+				dotImports = append(dotImports, imp)
+			}
+		}
+		if len(dotImports) == 0 {
+			return Reference{}, fmt.Errorf("undeclared identifier '%s'", name)
+		}
+		ref := Reference{Type: t, Kind: DotImportRef, Name: name}
+		if len(dotImports) == 1 {
+			ref.Path = dotImports[0].Path
+		}
+		return ref, nil
+	case Selector:
+		for _, imp := range s.Imports {
+			if imp.PackageName() == string(t.Left) {
+				return Reference{Type: t, Kind: ImportRef, Path: imp.Path, Name: string(t.Right)}, nil
+			}
+		}
+		return Reference{}, fmt.Errorf("no import for selector '%s'", t)
+	}
+	return Reference{}, fmt.Errorf("type %s is not an Ident or Selector", t)
+}
+
+// Resolve resolves every Ident and Selector used in t, in the order they appear.
+func (s *Scope) Resolve(t Type) ([]Reference, error) {
+	var refs []Reference
+	var err error
+	Walk(t, func(t Type) bool {
+		if err != nil {
+			return false
+		}
+		switch t.(type) {
+		case Ident, Selector:
+			var ref Reference
+			ref, err = s.Lookup(t)
+			refs = append(refs, ref)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve type %s: %w", t, err)
+	}
+	return refs, nil
+}
+
+// PackageName returns the name by which the import is referred to in the file.
+// For unnamed imports, this is guessed from the import path.
+// Dot and underscore imports return "." and "_" respectively.
+func (imp ImportDecl) PackageName() string {
+	if imp.Name != "" {
+		return imp.Name
+	}
+	return assumedPackageName(imp.Path)
+}
+
+// assumedPackageName guesses the package name from an import path,
+// skipping major version suffixes and go- prefixes.
+func assumedPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			dir := path.Dir(importPath)
+			if dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, notIdentifier); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+func notIdentifier(r rune) bool {
+	return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+}
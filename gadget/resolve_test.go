@@ -0,0 +1,77 @@
+package gadget
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScope_Resolve(t *testing.T) {
+	src := `package test
+
+import (
+	"io"
+	yml "gopkg.in/yaml.v2"
+	_ "embed"
+	. "strconv"
+	"github.com/foo/go-bar/v3"
+)
+
+type Local int
+
+type Full struct {
+	R   io.Reader
+	Y   yml.Node
+	B   bar.Thing
+	L   []Local
+	E   error
+	D   NumError
+}
+`
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	typ := f.GetTypes()["Full"]
+	refs, err := f.Scope().Resolve(typ)
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+	expected := []Reference{
+		{Type: Selector{Left: "io", Right: "Reader"}, Kind: ImportRef, Path: "io", Name: "Reader"},
+		{Type: Selector{Left: "yml", Right: "Node"}, Kind: ImportRef, Path: "gopkg.in/yaml.v2", Name: "Node"},
+		{Type: Selector{Left: "bar", Right: "Thing"}, Kind: ImportRef, Path: "github.com/foo/go-bar/v3", Name: "Thing"},
+		{Type: Ident("Local"), Kind: LocalRef, Name: "Local"},
+		{Type: Error, Kind: BuiltinRef, Name: "error"},
+		{Type: Ident("NumError"), Kind: DotImportRef, Path: "strconv", Name: "NumError"},
+	}
+	if !reflect.DeepEqual(expected, refs) {
+		t.Logf("want: %#v", expected)
+		t.Logf(" got: %#v", refs)
+		t.Fatalf("invalid references")
+	}
+
+	for _, s := range []string{"yaml.Node", "embed.FS", "os.File"} {
+		typ, err := ParseType(s)
+		if err != nil {
+			t.Fatalf("failed to parse type %s: %v", s, err)
+		}
+		if _, err := f.Scope().Lookup(typ); err == nil {
+			t.Errorf("expected %s not to resolve", s)
+		}
+	}
+}
+
+func TestAssumedPackageName(t *testing.T) {
+	for path, want := range map[string]string{
+		"fmt":                      "fmt",
+		"encoding/json":            "json",
+		"gopkg.in/yaml.v2":         "yaml",
+		"github.com/foo/go-bar/v3": "bar",
+		"github.com/foo/bar-baz":   "bar",
+	} {
+		if got := assumedPackageName(path); got != want {
+			t.Errorf("expected package name %s for %s, got %s", want, path, got)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package example
+
+type Kind uint16
+
+// Header precedes every Message.
+//
+//go:generate go run github.com/PieterD/pkg/gadget/cmd/shorthandgen
+type Header struct {
+	Version uint8
+	Kind    Kind
+}
@@ -0,0 +1,20 @@
+// Code generated by shorthandgen. DO NOT EDIT.
+
+package example
+
+import (
+	"github.com/PieterD/pkg/shorthand"
+)
+
+// MarshalShorthand encodes v using e.
+func (v Header) MarshalShorthand(e *shorthand.Encoder) {
+	e.Uint8(v.Version)
+	e.Uint16(uint16(v.Kind))
+}
+
+// UnmarshalShorthand decodes v using d.
+// Like the methods of the Decoder, it panics on invalid input; use shorthand.Recover to return the error instead.
+func (v *Header) UnmarshalShorthand(d *shorthand.Decoder) {
+	v.Version = d.Uint8("Version")
+	v.Kind = Kind(d.Uint16("Kind"))
+}
@@ -0,0 +1,25 @@
+// Package example contains structs with shorthand methods generated by shorthandgen.
+package example
+
+// Message is a wire message.
+//
+//go:generate go run github.com/PieterD/pkg/gadget/cmd/shorthandgen
+type Message struct {
+	Header  Header
+	ID      uint64
+	Offset  int32 `shorthand:",varint"`
+	Count   int
+	Ratio   float64
+	Active  bool
+	Name    string
+	Payload []byte
+	Digest  [4]byte
+	Tags    []string
+	Scores  map[string]int16
+	Headers []Header
+	Point   struct {
+		X, Y int32
+	}
+	Checksum uint32 `shorthand:",crc"`
+	Cached   string `shorthand:"-"`
+}
@@ -0,0 +1,80 @@
+// Code generated by shorthandgen. DO NOT EDIT.
+
+package example
+
+import (
+	"github.com/PieterD/pkg/shorthand"
+	"math"
+)
+
+// MarshalShorthand encodes v using e.
+func (v Message) MarshalShorthand(e *shorthand.Encoder) {
+	e.StartCRC()
+	v.Header.MarshalShorthand(e)
+	e.Uint64(v.ID)
+	e.VarInt64(int64(v.Offset))
+	e.VarInt(v.Count)
+	e.Uint64(math.Float64bits(v.Ratio))
+	if v.Active {
+		e.Uint8(1)
+	} else {
+		e.Uint8(0)
+	}
+	e.String(v.Name)
+	e.ByteSlice(v.Payload)
+	e.Bytes(v.Digest[:])
+	e.VarInt(len(v.Tags))
+	for i0 := range v.Tags {
+		e.String(v.Tags[i0])
+	}
+	e.VarInt(len(v.Scores))
+	for k0, x0 := range v.Scores {
+		e.String(k0)
+		e.Uint16(uint16(x0))
+	}
+	e.VarInt(len(v.Headers))
+	for i0 := range v.Headers {
+		v.Headers[i0].MarshalShorthand(e)
+	}
+	e.Uint32(uint32(v.Point.X))
+	e.Uint32(uint32(v.Point.Y))
+	e.PutCRC()
+}
+
+// UnmarshalShorthand decodes v using d.
+// Like the methods of the Decoder, it panics on invalid input; use shorthand.Recover to return the error instead.
+func (v *Message) UnmarshalShorthand(d *shorthand.Decoder) {
+	d.StartCRC()
+	v.Header.UnmarshalShorthand(d)
+	v.ID = d.Uint64("ID")
+	v.Offset = int32(d.VarInt64("Offset"))
+	v.Count = d.VarInt("Count")
+	v.Ratio = math.Float64frombits(d.Uint64("Ratio"))
+	v.Active = d.Uint8("Active") != 0
+	v.Name = d.String("Name")
+	v.Payload = d.ByteSlice("Payload")
+	copy(v.Digest[:], d.Bytes("Digest", len(v.Digest)))
+	v.Tags = nil
+	for i0, n0 := 0, d.VarInt("Tags"); i0 < n0; i0++ {
+		var x0 string
+		x0 = d.String("Tags")
+		v.Tags = append(v.Tags, x0)
+	}
+	v.Scores = make(map[string]int16)
+	for i0, n0 := 0, d.VarInt("Scores"); i0 < n0; i0++ {
+		var k0 string
+		var x0 int16
+		k0 = d.String("Scores")
+		x0 = int16(d.Uint16("Scores"))
+		v.Scores[k0] = x0
+	}
+	v.Headers = nil
+	for i0, n0 := 0, d.VarInt("Headers"); i0 < n0; i0++ {
+		var x0 Header
+		x0.UnmarshalShorthand(d)
+		v.Headers = append(v.Headers, x0)
+	}
+	v.Point.X = int32(d.Uint32("Point.X"))
+	v.Point.Y = int32(d.Uint32("Point.Y"))
+	d.CheckCRC("Checksum")
+}
@@ -0,0 +1,74 @@
+package example
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/PieterD/pkg/shorthand"
+)
+
+func testMessage() Message {
+	m := Message{
+		Header:  Header{Version: 3, Kind: 7},
+		ID:      1 << 40,
+		Offset:  -12,
+		Count:   42,
+		Ratio:   0.25,
+		Active:  true,
+		Name:    "hello",
+		Payload: []byte{1, 2, 3},
+		Digest:  [4]byte{9, 8, 7, 6},
+		Tags:    []string{"a", "b"},
+		Scores:  map[string]int16{"x": -1, "y": 2},
+		Headers: []Header{{Version: 1}, {Kind: 2}},
+	}
+	m.Point.X = -5
+	m.Point.Y = 6
+	return m
+}
+
+func TestMessage_RoundTrip(t *testing.T) {
+	m := testMessage()
+	m.Cached = "not encoded"
+	e := shorthand.NewEncoder(nil)
+	m.MarshalShorthand(e)
+
+	var got Message
+	err := func() (err error) {
+		defer shorthand.Recover(&err)
+		d := shorthand.NewDecoder(e.Buffer())
+		got.UnmarshalShorthand(d)
+		if d.Len() != 0 {
+			t.Fatalf("expected all bytes to be decoded, %d left", d.Len())
+		}
+		return nil
+	}()
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	expected := testMessage()
+	if !reflect.DeepEqual(expected, got) {
+		t.Logf("want: %#v", expected)
+		t.Logf(" got: %#v", got)
+		t.Fatalf("invalid round trip")
+	}
+}
+
+func TestMessage_InvalidCRC(t *testing.T) {
+	m := testMessage()
+	e := shorthand.NewEncoder(nil)
+	m.MarshalShorthand(e)
+	b := e.Copy()
+	b[len(b)-5] ^= 0xff
+
+	var got Message
+	err := func() (err error) {
+		defer shorthand.Recover(&err)
+		got.UnmarshalShorthand(shorthand.NewDecoder(b))
+		return nil
+	}()
+	if !errors.Is(err, shorthand.ErrInvalidCRC) {
+		t.Fatalf("expected invalid CRC error, got %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+// Package shorthandgen generates MarshalShorthand and UnmarshalShorthand methods for struct types,
+// encoding and decoding their fields with the shorthand package.
+//
+// Fields are encoded in declaration order:
+// fixed size integers, floats and bools as big endian, int and uint as varints,
+// strings and byte slices with a varint length prefix,
+// slices and maps as a varint length followed by their elements, with maps in iteration order,
+// and named struct types by calling their own MarshalShorthand and UnmarshalShorthand methods.
+//
+// The encoding of a field can be changed with a shorthand struct tag:
+//
+//	Skipped  string `shorthand:"-"`       // The field is not encoded.
+//	Count    int32  `shorthand:",varint"` // The integer is encoded as a varint.
+//	Checksum uint32 `shorthand:",crc"`    // A CRC of the fields since the previous checksum is encoded here.
+//
+// The value of a crc field itself is ignored when encoding and left untouched when decoding.
+// The Encoder and Decoder track a single CRC section, so CRC sections do not nest:
+// a struct with crc fields should not be encoded as part of the CRC section of another struct.
+package shorthandgen
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/PieterD/pkg/gadget"
+)
+
+// TagKey is the struct tag key used to configure the encoding of a field.
+const TagKey = "shorthand"
+
+const shorthandPath = "github.com/PieterD/pkg/shorthand"
+
+// Run is the shorthandgen command: it parses the shorthandgen flags from info.Args, and writes the methods for the struct type
+// selected by info to <file>_<suffix>.go.
+func Run(info *gadget.Info) error {
+	fs := flag.NewFlagSet("shorthandgen", flag.ContinueOnError)
+	suffix := fs.String("suffix", "shorthand", "suffix of the generated file name")
+	if err := info.ParseFlags(fs); err != nil {
+		return err
+	}
+	pkg, err := info.OpenPackage()
+	if err != nil {
+		return fmt.Errorf("failed to open package: %w", err)
+	}
+	target, err := info.Target()
+	if err != nil {
+		return err
+	}
+	if target.Kind != gadget.TypeTarget {
+		return info.Errorf("expected go:generate directive to precede a struct type, found %s %s", target.Kind, target.Name())
+	}
+	o, err := info.Output(*suffix)
+	if err != nil {
+		return fmt.Errorf("failed to create output: %w", err)
+	}
+	o.Generator = "shorthandgen"
+	if err := Generate(o, pkg, *target.Type); err != nil {
+		return err
+	}
+	return o.Write()
+}
+
+// Generate appends MarshalShorthand and UnmarshalShorthand methods for the struct type declared by decl to o.
+// decls is used to find the underlying types of named field types.
+func Generate(o *gadget.Output, decls gadget.Decls, decl gadget.TypeDecl) error {
+	if decl.Alias != nil {
+		return fmt.Errorf("%s: type %s is an alias", decl.Position, decl.Name)
+	}
+	if len(decl.TypeParams) > 0 {
+		return fmt.Errorf("%s: generic type %s is not supported", decl.Position, decl.Name)
+	}
+	s, ok := decl.Type.(gadget.Struct)
+	if !ok {
+		return fmt.Errorf("%s: type %s is not a struct", decl.Position, decl.Name)
+	}
+	g := &generator{
+		o:   o,
+		cmp: gadget.Comparer{Decls: decls},
+		enc: &strings.Builder{},
+		dec: &strings.Builder{},
+	}
+	if err := g.structFields("v.", "", s); err != nil {
+		return fmt.Errorf("failed to generate shorthand methods for %s: %w", decl.Name, err)
+	}
+	pkg := o.Import(shorthandPath)
+	o.Printf("// MarshalShorthand encodes v using e.\n")
+	o.Printf("func (v %s) MarshalShorthand(e *%s.Encoder) {\n", decl.Name, pkg)
+	if g.crc {
+		o.Printf("e.StartCRC()\n")
+	}
+	o.Printf("%s}\n\n", g.enc.String())
+	o.Printf("// UnmarshalShorthand decodes v using d.\n")
+	o.Printf("// Like the methods of the Decoder, it panics on invalid input; use %s.Recover to return the error instead.\n", pkg)
+	o.Printf("func (v *%s) UnmarshalShorthand(d *%s.Decoder) {\n", decl.Name, pkg)
+	if g.crc {
+		o.Printf("d.StartCRC()\n")
+	}
+	o.Printf("%s}\n\n", g.dec.String())
+	return nil
+}
+
+// generator writes the bodies of the encoding and decoding methods side by side.
+type generator struct {
+	o          *gadget.Output
+	cmp        gadget.Comparer
+	enc        *strings.Builder
+	dec        *strings.Builder
+	depth      int  // The nesting depth of loops, used to name loop variables.
+	crc        bool // Set if any crc field was found.
+	restartCRC bool // Set if a new CRC section starts at the next field.
+}
+
+func (g *generator) encf(format string, args ...interface{}) {
+	fmt.Fprintf(g.enc, format+"\n", args...)
+}
+
+func (g *generator) decf(format string, args ...interface{}) {
+	fmt.Fprintf(g.dec, format+"\n", args...)
+}
+
+// structFields generates the code for the fields of s.
+// Each field is accessed as prefix followed by its name, and labeled as label followed by its name.
+func (g *generator) structFields(prefix string, label string, s gadget.Struct) error {
+	for _, field := range s.Fields {
+		name := field.FieldName()
+		if name == "_" {
+			continue
+		}
+		tags, err := field.Tags()
+		if err != nil {
+			return err
+		}
+		tag, _ := tags.Get(TagKey)
+		if tag.Name == "-" {
+			continue
+		}
+		expr := prefix + name
+		fieldLabel := label + name
+		if g.restartCRC {
+			g.encf("e.StartCRC()")
+			g.decf("d.StartCRC()")
+			g.restartCRC = false
+		}
+		if tag.HasOption("crc") {
+			if u, ok := g.cmp.Underlying(field.Type); !ok || u != gadget.Uint32 {
+				return fmt.Errorf("%s: crc field %s must be a uint32", field.Position, fieldLabel)
+			}
+			g.crc = true
+			g.restartCRC = true
+			g.encf("e.PutCRC()")
+			g.decf("d.CheckCRC(%q)", fieldLabel)
+			continue
+		}
+		if err := g.value(expr, fieldLabel, field.Type, tag.HasOption("varint")); err != nil {
+			return fmt.Errorf("%s: field %s: %w", field.Position, fieldLabel, err)
+		}
+	}
+	return nil
+}
+
+// integer describes how a basic type is encoded: the Encoder and Decoder method, and the Go type it works with.
+type integer struct {
+	method string
+	native string
+}
+
+var fixedEncodings = map[gadget.Ident]integer{
+	gadget.Uint8:   {"Uint8", "uint8"},
+	gadget.Byte:    {"Uint8", "uint8"},
+	gadget.Int8:    {"Uint8", "uint8"},
+	gadget.Uint16:  {"Uint16", "uint16"},
+	gadget.Int16:   {"Uint16", "uint16"},
+	gadget.Uint32:  {"Uint32", "uint32"},
+	gadget.Int32:   {"Uint32", "uint32"},
+	gadget.Rune:    {"Uint32", "uint32"},
+	gadget.Uint64:  {"Uint64", "uint64"},
+	gadget.Int64:   {"Uint64", "uint64"},
+	gadget.Int:     {"VarInt", "int"},
+	gadget.Uint:    {"VarUint64", "uint64"},
+	gadget.Uintptr: {"VarUint64", "uint64"},
+}
+
+var varintEncodings = map[gadget.Ident]integer{
+	gadget.Int8:  {"VarInt64", "int64"},
+	gadget.Int16: {"VarInt64", "int64"},
+	gadget.Int32: {"VarInt64", "int64"},
+	gadget.Rune:  {"VarInt64", "int64"},
+	gadget.Int64: {"VarInt64", "int64"},
+	gadget.Int:   {"VarInt", "int"},
+}
+
+// value generates the code for a single value of type t, accessed as expr.
+// expr must be addressable.
+func (g *generator) value(expr string, label string, t gadget.Type, varint bool) error {
+	u, ok := g.cmp.Underlying(t)
+	if !ok {
+		// Types declared elsewhere are expected to have their own shorthand methods.
+		return g.methods(expr, t)
+	}
+	typeName := g.o.Type(t)
+	switch u := u.(type) {
+	case gadget.Ident:
+		if enc, ok := fixedEncodings[u]; ok {
+			if varint {
+				enc, ok = varintEncodings[u]
+				if !ok {
+					enc = integer{"VarUint64", "uint64"}
+				}
+			}
+			g.integer(expr, label, typeName, enc)
+			return nil
+		}
+		if varint {
+			return fmt.Errorf("varint encoding is not supported for type %s", t)
+		}
+		switch u {
+		case gadget.Bool:
+			g.encf("if %s {", expr)
+			g.encf("e.Uint8(1)")
+			g.encf("} else {")
+			g.encf("e.Uint8(0)")
+			g.encf("}")
+			g.decf("%s = %s", expr, convert(typeName, "bool", fmt.Sprintf("d.Uint8(%q) != 0", label)))
+		case gadget.String:
+			g.encf("e.String(%s)", convert("string", typeName, expr))
+			g.decf("%s = %s", expr, convert(typeName, "string", fmt.Sprintf("d.String(%q)", label)))
+		case gadget.Float32, gadget.Float64:
+			bits := "32"
+			if u == gadget.Float64 {
+				bits = "64"
+			}
+			math := g.o.Import("math")
+			g.encf("e.Uint%s(%s.Float%sbits(%s))", bits, math, bits, convert("float"+bits, typeName, expr))
+			g.decf("%s = %s", expr, convert(typeName, "float"+bits, fmt.Sprintf("%s.Float%sfrombits(d.Uint%s(%q))", math, bits, bits, label)))
+		default:
+			return fmt.Errorf("unsupported type %s", t)
+		}
+		return nil
+	case gadget.Struct:
+		if _, anonymous := t.(gadget.Struct); anonymous {
+			return g.structFields(expr+".", label+".", u)
+		}
+		return g.methods(expr, t)
+	case gadget.Slice:
+		if g.isByte(u.Elem) {
+			g.encf("e.ByteSlice(%s)", convert("[]byte", typeName, expr))
+			g.decf("%s = %s", expr, convert(typeName, "[]byte", fmt.Sprintf("d.ByteSlice(%q)", label)))
+			return nil
+		}
+		i, n, x := g.loopVars()
+		defer g.endLoop()
+		g.encf("e.VarInt(len(%s))", expr)
+		g.encf("for %s := range %s {", i, expr)
+		g.decf("%s = nil", expr)
+		g.decf("for %s, %s := 0, d.VarInt(%q); %s < %s; %s++ {", i, n, label, i, n, i)
+		g.decf("var %s %s", x, g.o.Type(u.Elem))
+		if err := g.element(fmt.Sprintf("%s[%s]", expr, i), x, label, u.Elem, varint); err != nil {
+			return err
+		}
+		g.encf("}")
+		g.decf("%s = append(%s, %s)", expr, expr, x)
+		g.decf("}")
+		return nil
+	case gadget.Array:
+		if g.isByte(u.Elem) {
+			g.encf("e.Bytes(%s[:])", expr)
+			g.decf("copy(%s[:], d.Bytes(%q, len(%s)))", expr, label, expr)
+			return nil
+		}
+		i, _, _ := g.loopVars()
+		defer g.endLoop()
+		g.encf("for %s := range %s {", i, expr)
+		g.decf("for %s := range %s {", i, expr)
+		elem := fmt.Sprintf("%s[%s]", expr, i)
+		if err := g.value(elem, label, u.Elem, varint); err != nil {
+			return err
+		}
+		g.encf("}")
+		g.decf("}")
+		return nil
+	case gadget.Map:
+		i, n, x := g.loopVars()
+		defer g.endLoop()
+		k := "k" + x[1:]
+		g.encf("e.VarInt(len(%s))", expr)
+		g.encf("for %s, %s := range %s {", k, x, expr)
+		g.decf("%s = make(%s)", expr, typeName)
+		g.decf("for %s, %s := 0, d.VarInt(%q); %s < %s; %s++ {", i, n, label, i, n, i)
+		g.decf("var %s %s", k, g.o.Type(u.Key))
+		g.decf("var %s %s", x, g.o.Type(u.Value))
+		if err := g.element(k, k, label, u.Key, varint); err != nil {
+			return err
+		}
+		if err := g.element(x, x, label, u.Value, varint); err != nil {
+			return err
+		}
+		g.encf("}")
+		g.decf("%s[%s] = %s", expr, k, x)
+		g.decf("}")
+		return nil
+	}
+	return fmt.Errorf("unsupported type %s", t)
+}
+
+// element generates the code for an element of a slice or map,
+// which is read from encExpr when encoding, and decoded into the local variable decExpr.
+func (g *generator) element(encExpr string, decExpr string, label string, t gadget.Type, varint bool) error {
+	// Generate each side with the other side discarded, so that they can use different expressions.
+	enc, dec := g.enc, g.dec
+	defer func() {
+		g.enc, g.dec = enc, dec
+	}()
+	g.dec = &strings.Builder{}
+	if err := g.value(encExpr, label, t, varint); err != nil {
+		return err
+	}
+	g.enc, g.dec = &strings.Builder{}, dec
+	return g.value(decExpr, label, t, varint)
+}
+
+// methods generates calls to the shorthand methods of a named type.
+func (g *generator) methods(expr string, t gadget.Type) error {
+	switch t.(type) {
+	case gadget.Ident, gadget.Selector, gadget.Instance:
+	default:
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	g.encf("%s.MarshalShorthand(e)", expr)
+	g.decf("%s.UnmarshalShorthand(d)", expr)
+	return nil
+}
+
+// integer generates the code for an integer encoded with enc.
+func (g *generator) integer(expr string, label string, typeName string, enc integer) {
+	g.encf("e.%s(%s)", enc.method, convert(enc.native, typeName, expr))
+	g.decf("%s = %s", expr, convert(typeName, enc.native, fmt.Sprintf("d.%s(%q)", enc.method, label)))
+}
+
+// loopVars returns the names of the variables used for a loop at the current depth, and increases the depth.
+func (g *generator) loopVars() (i, n, x string) {
+	suffix := fmt.Sprint(g.depth)
+	g.depth++
+	return "i" + suffix, "n" + suffix, "x" + suffix
+}
+
+func (g *generator) endLoop() {
+	g.depth--
+}
+
+// isByte returns true if t is byte or uint8, possibly through an alias.
+// Named types with byte as their underlying type are not, as []B can not be converted to []byte.
+func (g *generator) isByte(t gadget.Type) bool {
+	cmp := gadget.Comparer{Decls: g.cmp.Decls, ResolveAliases: true, ByteRuneAliases: true}
+	return cmp.Identical(t, gadget.Byte)
+}
+
+// convert returns expr converted to the type to, unless the type of expr, from, is already the same.
+func convert(to string, from string, expr string) string {
+	if to == from {
+		return expr
+	}
+	return to + "(" + expr + ")"
+}
@@ -0,0 +1,88 @@
+package shorthandgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PieterD/pkg/gadget"
+	"github.com/PieterD/pkg/gadget/gadgettest"
+)
+
+// TestGenerate_Example checks that the generated files in the example package are up to date.
+func TestGenerate_Example(t *testing.T) {
+	for _, file := range []string{"example/header.go", "example/message.go"} {
+		gadgettest.Golden(t, Run, gadgettest.Case{File: file}, "")
+	}
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	src := "package test\n\n" +
+		"type NotStruct int\n\n" +
+		"type Pointer struct {\n\tP *int\n}\n\n" +
+		"type BadCRC struct {\n\tC string `shorthand:\",crc\"`\n}\n\n" +
+		"type BadVarint struct {\n\tS string `shorthand:\",varint\"`\n}\n\n" +
+		"type BadTag struct {\n\tS string `shorthand`\n}\n"
+	f, err := gadget.NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	for name, position := range map[string]string{
+		"NotStruct": "test.go:3",
+		"Pointer":   "test.go:6",
+		"BadCRC":    "test.go:10",
+		"BadVarint": "test.go:14",
+		"BadTag":    "test.go:18",
+	} {
+		decl, _ := f.LookupType(name)
+		err := Generate(gadget.NewOutput("test_shorthand.go", "test", f.Scope()), f, decl)
+		if err == nil {
+			t.Errorf("expected error for %s", name)
+			continue
+		}
+		if !strings.Contains(err.Error(), position+":") {
+			t.Errorf("expected error for %s to mention %s, got %v", name, position, err)
+		}
+	}
+}
+
+func TestGenerate_NamedBytes(t *testing.T) {
+	src := `package test
+
+type B byte
+
+type octet = byte
+
+type Blob struct {
+	Raw   []B
+	Fixed [2]B
+	Octets []octet
+}
+`
+	f, err := gadget.NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	decl, _ := f.LookupType("Blob")
+	o := gadget.NewOutput("test_shorthand.go", "test", f.Scope())
+	if err := Generate(o, f, decl); err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+	got, err := o.Bytes()
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	for _, want := range []string{
+		"for i0 := range v.Raw {",
+		"for i0 := range v.Fixed {",
+		"e.ByteSlice([]byte(v.Octets))",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Logf("got: %s", got)
+			t.Fatalf("expected generated code to contain %s", want)
+		}
+	}
+	if strings.Contains(string(got), "[]byte(v.Raw)") || strings.Contains(string(got), "v.Fixed[:]") {
+		t.Logf("got: %s", got)
+		t.Fatalf("expected named byte types not to be converted to []byte")
+	}
+}
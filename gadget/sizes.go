@@ -0,0 +1,266 @@
+package gadget
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Sizes computes the memory layout of types the way the gc compiler does for a target architecture.
+type Sizes struct {
+	WordSize int64 // The size of a pointer, int and uintptr, in bytes.
+	MaxAlign int64 // The maximum alignment of any type, in bytes.
+	Decls    Decls // Used to look up declared types. May be nil, in which case only unnamed and predeclared types are supported.
+}
+
+// archSizes holds the word size and maximum alignment of the architectures supported by gc.
+var archSizes = map[string]Sizes{
+	"386":      {WordSize: 4, MaxAlign: 4},
+	"amd64":    {WordSize: 8, MaxAlign: 8},
+	"amd64p32": {WordSize: 4, MaxAlign: 8},
+	"arm":      {WordSize: 4, MaxAlign: 4},
+	"arm64":    {WordSize: 8, MaxAlign: 8},
+	"loong64":  {WordSize: 8, MaxAlign: 8},
+	"mips":     {WordSize: 4, MaxAlign: 4},
+	"mipsle":   {WordSize: 4, MaxAlign: 4},
+	"mips64":   {WordSize: 8, MaxAlign: 8},
+	"mips64le": {WordSize: 8, MaxAlign: 8},
+	"ppc64":    {WordSize: 8, MaxAlign: 8},
+	"ppc64le":  {WordSize: 8, MaxAlign: 8},
+	"riscv64":  {WordSize: 8, MaxAlign: 8},
+	"s390x":    {WordSize: 8, MaxAlign: 8},
+	"sparc64":  {WordSize: 8, MaxAlign: 8},
+	"wasm":     {WordSize: 8, MaxAlign: 8},
+}
+
+// SizesFor returns the Sizes for the given GOARCH, like amd64, using decls to look up declared types.
+func SizesFor(arch string, decls Decls) (*Sizes, error) {
+	s, ok := archSizes[arch]
+	if !ok {
+		return nil, fmt.Errorf("unknown architecture '%s'", arch)
+	}
+	s.Decls = decls
+	return &s, nil
+}
+
+// Sizes returns the Sizes for the architecture go generate was invoked for,
+// looking up declared types in the package of the go:generate directive.
+func (i *Info) Sizes() (*Sizes, error) {
+	pkg, err := i.OpenPackage()
+	if err != nil {
+		return nil, err
+	}
+	return SizesFor(i.Arch, pkg)
+}
+
+// Sizeof returns the size of a value of type t, in bytes.
+func (s *Sizes) Sizeof(t Type) (int64, error) {
+	size, _, err := s.layout(t, make(map[Ident]bool))
+	return size, err
+}
+
+// Alignof returns the alignment of a value of type t, in bytes.
+func (s *Sizes) Alignof(t Type) (int64, error) {
+	_, align, err := s.layout(t, make(map[Ident]bool))
+	return align, err
+}
+
+// Offsetsof returns the offset of each field of a struct, in bytes.
+func (s *Sizes) Offsetsof(st Struct) ([]int64, error) {
+	layout, err := s.StructLayout(st)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make([]int64, len(layout.Fields))
+	for i, field := range layout.Fields {
+		offsets[i] = field.Offset
+	}
+	return offsets, nil
+}
+
+// StructLayout describes the memory layout of a struct.
+type StructLayout struct {
+	Size   int64
+	Align  int64
+	Fields []FieldLayout
+}
+
+// FieldLayout describes the memory layout of a struct field.
+type FieldLayout struct {
+	StructField
+	Offset  int64
+	Size    int64
+	Align   int64
+	Padding int64 // The padding inserted before the field.
+}
+
+// Padding returns the total number of padding bytes in the struct, including the padding at its end.
+func (l *StructLayout) Padding() int64 {
+	used := int64(0)
+	for _, field := range l.Fields {
+		used += field.Size
+	}
+	return l.Size - used
+}
+
+// StructLayout computes the memory layout of a struct.
+func (s *Sizes) StructLayout(st Struct) (*StructLayout, error) {
+	return s.structLayout(st, make(map[Ident]bool))
+}
+
+// Optimize returns the struct with its fields reordered to minimize padding.
+// Fields are ordered by decreasing alignment, with zero-sized fields first;
+// fields with the same alignment keep their relative order.
+// The struct is returned unchanged if reordering does not reduce its size.
+func (s *Sizes) Optimize(st Struct) (Struct, error) {
+	layout, err := s.StructLayout(st)
+	if err != nil {
+		return Struct{}, err
+	}
+	fields := append([]FieldLayout(nil), layout.Fields...)
+	sort.SliceStable(fields, func(i, j int) bool {
+		if (fields[i].Size == 0) != (fields[j].Size == 0) {
+			return fields[i].Size == 0
+		}
+		return fields[i].Align > fields[j].Align
+	})
+	optimized := Struct{Fields: make([]StructField, len(fields))}
+	for i, field := range fields {
+		optimized.Fields[i] = field.StructField
+	}
+	optimizedLayout, err := s.StructLayout(optimized)
+	if err != nil {
+		return Struct{}, err
+	}
+	if optimizedLayout.Size >= layout.Size {
+		return st, nil
+	}
+	return optimized, nil
+}
+
+func (s *Sizes) structLayout(st Struct, visiting map[Ident]bool) (*StructLayout, error) {
+	layout := &StructLayout{Align: 1}
+	offset := int64(0)
+	for _, field := range st.Fields {
+		size, align, err := s.layout(field.Type, visiting)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute layout of field %s: %w", field.FieldName(), err)
+		}
+		aligned := alignTo(offset, align)
+		layout.Fields = append(layout.Fields, FieldLayout{
+			StructField: field,
+			Offset:      aligned,
+			Size:        size,
+			Align:       align,
+			Padding:     aligned - offset,
+		})
+		offset = aligned + size
+		if align > layout.Align {
+			layout.Align = align
+		}
+	}
+	// gc pads a trailing zero-sized field, so that its address does not point past the end of the struct.
+	if n := len(layout.Fields); n > 0 && layout.Fields[n-1].Size == 0 && offset > 0 {
+		offset++
+	}
+	layout.Size = alignTo(offset, layout.Align)
+	return layout, nil
+}
+
+// layout returns the size and alignment of t.
+// visiting contains the declared types whose layout is being computed, to detect invalid recursive types.
+func (s *Sizes) layout(t Type, visiting map[Ident]bool) (int64, int64, error) {
+	if id, ok := t.(Ident); ok && !builtinTypes[string(id)] {
+		if visiting[id] {
+			return 0, 0, fmt.Errorf("invalid recursive type %s", id)
+		}
+		visiting[id] = true
+		defer delete(visiting, id)
+	}
+	if t == (Selector{Left: "unsafe", Right: "Pointer"}) {
+		return s.WordSize, s.WordSize, nil
+	}
+	u, ok := Comparer{Decls: s.Decls}.Underlying(t)
+	if !ok {
+		return 0, 0, fmt.Errorf("type %s is not declared in the package", t)
+	}
+	switch u := u.(type) {
+	case Ident:
+		size, ok := s.basicSize(u)
+		if !ok {
+			return 0, 0, fmt.Errorf("unknown type %s", u)
+		}
+		align := size
+		switch u {
+		case String:
+			align = s.WordSize
+		case Complex64, Complex128:
+			align = size / 2
+		}
+		if align > s.MaxAlign {
+			align = s.MaxAlign
+		}
+		return size, align, nil
+	case Pointer, Map, Chan, Func:
+		return s.WordSize, s.WordSize, nil
+	case Slice:
+		return 3 * s.WordSize, s.WordSize, nil
+	case Interface:
+		return 2 * s.WordSize, s.WordSize, nil
+	case Array:
+		n, err := s.arrayLen(u)
+		if err != nil {
+			return 0, 0, err
+		}
+		size, align, err := s.layout(u.Elem, visiting)
+		if err != nil {
+			return 0, 0, err
+		}
+		return size * int64(n), align, nil
+	case Struct:
+		layout, err := s.structLayout(u, visiting)
+		if err != nil {
+			return 0, 0, err
+		}
+		return layout.Size, layout.Align, nil
+	}
+	return 0, 0, fmt.Errorf("can not compute the layout of type %s", u)
+}
+
+// basicSize returns the size of a predeclared type.
+func (s *Sizes) basicSize(t Ident) (int64, bool) {
+	switch t {
+	case Bool, Int8, Uint8, Byte:
+		return 1, true
+	case Int16, Uint16:
+		return 2, true
+	case Int32, Uint32, Rune, Float32:
+		return 4, true
+	case Int64, Uint64, Float64, Complex64:
+		return 8, true
+	case Complex128:
+		return 16, true
+	case Int, Uint, Uintptr:
+		return s.WordSize, true
+	case String:
+		return 2 * s.WordSize, true
+	}
+	return 0, false
+}
+
+// arrayLen returns the length of an array, evaluating its length expression if Decls is able to.
+func (s *Sizes) arrayLen(a Array) (int, error) {
+	if a.Len == "" {
+		return a.Size, nil
+	}
+	evaluator, ok := s.Decls.(interface {
+		ArrayLen(a Array) (int, error)
+	})
+	if !ok {
+		return 0, fmt.Errorf("length of array %s can not be evaluated without a File or Package", a)
+	}
+	return evaluator.ArrayLen(a)
+}
+
+func alignTo(offset int64, align int64) int64 {
+	return (offset + align - 1) / align * align
+}
@@ -0,0 +1,138 @@
+package gadget
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+type sizesSample struct {
+	A bool
+	B int64
+	C [3]uint16
+	D string
+	E []byte
+	F map[string]int
+	G *sizesSample
+	H complex64
+	I struct{}
+}
+
+func TestSizes(t *testing.T) {
+	src := `package test
+
+const Count = 3
+
+type Sample struct {
+	A bool
+	B int64
+	C [Count]uint16
+	D string
+	E []byte
+	F map[string]int
+	G *Sample
+	H complex64
+	I struct{}
+}
+
+type Loop struct {
+	Next [1]Loop
+}
+`
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	sizes, err := SizesFor(runtime.GOARCH, f)
+	if err != nil {
+		t.Skipf("architecture not supported: %v", err)
+	}
+	layout, err := sizes.StructLayout(f.Types[0].Type.(Struct))
+	if err != nil {
+		t.Fatalf("failed to compute layout: %v", err)
+	}
+	sample := reflect.TypeOf(sizesSample{})
+	if layout.Size != int64(sample.Size()) || layout.Align != int64(sample.Align()) {
+		t.Fatalf("expected size %d and alignment %d, got %d and %d", sample.Size(), sample.Align(), layout.Size, layout.Align)
+	}
+	for i, field := range layout.Fields {
+		if want := int64(sample.Field(i).Offset); field.Offset != want {
+			t.Fatalf("field %s: expected offset %d, got %d", field.Name, want, field.Offset)
+		}
+	}
+	if size, err := sizes.Sizeof(Ident("Sample")); err != nil || size != int64(unsafe.Sizeof(sizesSample{})) {
+		t.Fatalf("unexpected size of Sample: %d, %v", size, err)
+	}
+	if _, err := sizes.Sizeof(Ident("Loop")); err == nil {
+		t.Fatalf("expected error for recursive type")
+	}
+	if _, err := sizes.Sizeof(Selector{Left: "time", Right: "Time"}); err == nil {
+		t.Fatalf("expected error for type from another package")
+	}
+}
+
+func TestSizes_Arch(t *testing.T) {
+	sizes, err := SizesFor("386", nil)
+	if err != nil {
+		t.Fatalf("failed to get sizes: %v", err)
+	}
+	st := Struct{Fields: []StructField{
+		{Name: "A", Type: Bool},
+		{Name: "B", Type: Int64},
+		{Name: "C", Type: Complex128},
+		{Name: "D", Type: Slice{Elem: Byte}},
+	}}
+	offsets, err := sizes.Offsetsof(st)
+	if err != nil {
+		t.Fatalf("failed to compute offsets: %v", err)
+	}
+	if want := []int64{0, 4, 12, 28}; !reflect.DeepEqual(offsets, want) {
+		t.Fatalf("expected offsets %v, got %v", want, offsets)
+	}
+	if size, err := sizes.Sizeof(st); err != nil || size != 40 {
+		t.Fatalf("expected size 40, got %d, %v", size, err)
+	}
+	if _, err := SizesFor("z80", nil); err == nil {
+		t.Fatalf("expected error for unknown architecture")
+	}
+}
+
+func TestSizes_Optimize(t *testing.T) {
+	sizes, err := SizesFor("amd64", nil)
+	if err != nil {
+		t.Fatalf("failed to get sizes: %v", err)
+	}
+	st := Struct{Fields: []StructField{
+		{Name: "A", Type: Bool},
+		{Name: "B", Type: Int64},
+		{Name: "C", Type: Int16},
+		{Name: "D", Type: Struct{}},
+		{Name: "E", Type: Bool},
+		{Name: "F", Type: Pointer{Elem: Int}},
+	}}
+	layout, err := sizes.StructLayout(st)
+	if err != nil {
+		t.Fatalf("failed to compute layout: %v", err)
+	}
+	if layout.Size != 32 || layout.Padding() != 12 {
+		t.Fatalf("expected size 32 with 12 bytes of padding, got %d with %d", layout.Size, layout.Padding())
+	}
+	optimized, err := sizes.Optimize(st)
+	if err != nil {
+		t.Fatalf("failed to optimize: %v", err)
+	}
+	want := "struct{D struct{}; B int64; F *int; C int16; A bool; E bool}"
+	if s := optimized.String(); s != want {
+		t.Logf("want: %s", want)
+		t.Logf(" got: %s", s)
+		t.Fatalf("unexpected field order")
+	}
+	if size, err := sizes.Sizeof(optimized); err != nil || size != 24 {
+		t.Fatalf("expected optimized size 24, got %d, %v", size, err)
+	}
+	if again, err := sizes.Optimize(optimized); err != nil || again.String() != want {
+		t.Fatalf("expected optimized struct to be unchanged, got %s, %v", again, err)
+	}
+}
@@ -0,0 +1,145 @@
+package gadget
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tag is a single key:"value" pair of a struct tag.
+// The value is split on commas into a name and options, like json:"name,omitempty".
+type Tag struct {
+	Key     string
+	Name    string   // The part of the value before the first comma.
+	Options []string // The comma-separated parts of the value after the name.
+}
+
+// Value returns the value of the tag, joining the name and options.
+func (t Tag) Value() string {
+	return strings.Join(append([]string{t.Name}, t.Options...), ",")
+}
+
+// HasOption returns true if the tag has the given option.
+func (t Tag) HasOption(option string) bool {
+	for _, o := range t.Options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Tag) String() string {
+	return t.Key + ":" + strconv.Quote(t.Value())
+}
+
+// Tags is a parsed struct tag, with its key:"value" pairs in order.
+type Tags []Tag
+
+// ParseTags parses a struct tag, following the conventional format described in reflect.StructTag.
+// Unlike reflect.StructTag, malformed tags result in an error.
+func ParseTags(tag string) (Tags, error) {
+	var tags Tags
+	for {
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			return tags, nil
+		}
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 {
+			return nil, fmt.Errorf("invalid character %q in tag key", tag[0])
+		}
+		if i == len(tag) || tag[i] != ':' {
+			return nil, fmt.Errorf("missing ':' after tag key '%s'", tag[:i])
+		}
+		if i+1 == len(tag) || tag[i+1] != '"' {
+			return nil, fmt.Errorf("missing quoted value for tag key '%s'", tag[:i])
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, fmt.Errorf("unterminated value for tag key '%s'", key)
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for tag key '%s': %w", key, err)
+		}
+		tag = tag[i+1:]
+		if tag != "" && tag[0] != ' ' {
+			return nil, fmt.Errorf("missing space after value of tag key '%s'", key)
+		}
+		if _, ok := tags.Get(key); ok {
+			return nil, fmt.Errorf("duplicate tag key '%s'", key)
+		}
+		parts := strings.Split(value, ",")
+		tags = append(tags, Tag{
+			Key:     key,
+			Name:    parts[0],
+			Options: parts[1:],
+		})
+	}
+}
+
+// Get returns the tag with the given key.
+func (t Tags) Get(key string) (Tag, bool) {
+	for _, tag := range t {
+		if tag.Key == key {
+			return tag, true
+		}
+	}
+	return Tag{}, false
+}
+
+// Set returns the tags with tag replacing the tag with the same key.
+// If there is no tag with the same key, tag is appended.
+func (t Tags) Set(tag Tag) Tags {
+	set := append(Tags(nil), t...)
+	for i := range set {
+		if set[i].Key == tag.Key {
+			set[i] = tag
+			return set
+		}
+	}
+	return append(set, tag)
+}
+
+// Delete returns the tags without the tag with the given key.
+func (t Tags) Delete(key string) Tags {
+	var deleted Tags
+	for _, tag := range t {
+		if tag.Key != key {
+			deleted = append(deleted, tag)
+		}
+	}
+	return deleted
+}
+
+// String returns the tags as a struct tag, without the surrounding backquotes.
+func (t Tags) String() string {
+	parts := make([]string, len(t))
+	for i, tag := range t {
+		parts[i] = tag.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// Tags parses the tag of the field.
+// Errors are tied to the position of the field.
+func (f StructField) Tags() (Tags, error) {
+	tags, err := ParseTags(f.Tag)
+	if err != nil {
+		return nil, PosError{Position: f.Position, Err: fmt.Errorf("invalid tag on field %s: %w", f.FieldName(), err)}
+	}
+	return tags, nil
+}
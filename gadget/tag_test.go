@@ -0,0 +1,80 @@
+package gadget
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	raw := `json:"name,omitempty" xml:"-" db:"a \"quoted\" value"`
+	tags, err := ParseTags(raw)
+	if err != nil {
+		t.Fatalf("failed to parse tags: %v", err)
+	}
+	expected := Tags{
+		{Key: "json", Name: "name", Options: []string{"omitempty"}},
+		{Key: "xml", Name: "-", Options: []string{}},
+		{Key: "db", Name: `a "quoted" value`, Options: []string{}},
+	}
+	if !reflect.DeepEqual(expected, tags) {
+		t.Logf("want: %#v", expected)
+		t.Logf(" got: %#v", tags)
+		t.Fatalf("invalid tags")
+	}
+	if s := tags.String(); s != raw {
+		t.Fatalf("expected tags to round-trip to %q, got %q", raw, s)
+	}
+
+	json, ok := tags.Get("json")
+	if !ok || !json.HasOption("omitempty") || json.HasOption("string") {
+		t.Fatalf("unexpected json tag: %#v", json)
+	}
+	json.Options = append(json.Options, "string")
+	tags = tags.Set(json).Set(Tag{Key: "yaml", Name: "name"}).Delete("xml")
+	expectedString := `json:"name,omitempty,string" db:"a \"quoted\" value" yaml:"name"`
+	if s := tags.String(); s != expectedString {
+		t.Fatalf("expected %q, got %q", expectedString, s)
+	}
+	if value := reflect.StructTag(expectedString).Get("db"); value != `a "quoted" value` {
+		t.Fatalf("rewritten tag not understood by reflect, got %q", value)
+	}
+}
+
+func TestParseTags_Invalid(t *testing.T) {
+	for _, tag := range []string{
+		`json`,
+		`json:name`,
+		`json:"name`,
+		`json:"name"xml:"-"`,
+		`json:"a" json:"b"`,
+		`:"name"`,
+		`json:"\q"`,
+	} {
+		if tags, err := ParseTags(tag); err == nil {
+			t.Errorf("expected error for tag %q, got %#v", tag, tags)
+		}
+	}
+}
+
+func TestStructField_Tags(t *testing.T) {
+	src := "package test\n\ntype T struct {\n\tGood int `json:\"good\"`\n\tBad  int `json:bad`\n}\n"
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	fields := f.GetTypes()["T"].(Struct).Fields
+	tags, err := fields[0].Tags()
+	if err != nil {
+		t.Fatalf("failed to parse tags: %v", err)
+	}
+	if tag, ok := tags.Get("json"); !ok || tag.Name != "good" {
+		t.Fatalf("unexpected tags: %#v", tags)
+	}
+	_, err = fields[1].Tags()
+	var posErr PosError
+	if !errors.As(err, &posErr) || posErr.Position.String() != "test.go:5:2" {
+		t.Fatalf("expected error at test.go:5:2, got %v", err)
+	}
+}
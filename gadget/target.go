@@ -0,0 +1,161 @@
+package gadget
+
+import (
+	"fmt"
+)
+
+// TargetKind is the kind of declaration a go:generate directive targets.
+type TargetKind int
+
+const (
+	UnknownTarget TargetKind = iota
+	TypeTarget
+	FuncTarget
+	ConstTarget
+	VarTarget
+)
+
+func (k TargetKind) String() string {
+	switch k {
+	case TypeTarget:
+		return "TYPE"
+	case FuncTarget:
+		return "FUNC"
+	case ConstTarget:
+		return "CONST"
+	case VarTarget:
+		return "VAR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Target is the declaration following a go:generate directive.
+// Only the field matching Kind is set.
+type Target struct {
+	Kind TargetKind
+	Position
+	Type   *TypeDecl
+	Func   *FuncDecl
+	Consts []ConstDecl // All constants of the const block the directive targets.
+	Vars   []VarDecl   // All variables of the var spec the directive targets, like a, b in var a, b int.
+}
+
+// Name returns the name of the targeted declaration.
+// For const blocks and var specs, the name of the first constant or variable is returned.
+func (t Target) Name() string {
+	switch t.Kind {
+	case TypeTarget:
+		return t.Type.Name
+	case FuncTarget:
+		return t.Func.Name
+	case ConstTarget:
+		return t.Consts[0].Name
+	case VarTarget:
+		return t.Vars[0].Name
+	}
+	return ""
+}
+
+// Target finds the first declaration following the go:generate directive.
+// Doc comments, blank lines and the opening of grouped declarations like type ( may sit in between.
+// Inside a grouped declaration, the spec following the directive is targeted, except for const blocks,
+// which are always targeted as a whole.
+// If Name is set, the declaration with that name is targeted instead, regardless of the directive.
+func (i *Info) Target() (Target, error) {
+	if i.file == nil {
+		return Target{}, fmt.Errorf("Info.Target called before Info.Open")
+	}
+	if i.Name != "" {
+		target, ok := findNamedTarget(i.file, i.Name)
+		if !ok {
+			return Target{}, i.Errorf("no declaration named %s in %s", i.Name, i.File)
+		}
+		return target, nil
+	}
+	target, ok := findTarget(i.file, i.Line)
+	if !ok {
+		return Target{}, i.Errorf("no declaration follows the go:generate directive")
+	}
+	return target, nil
+}
+
+// findTarget finds the first declaration in f after the given line.
+func findTarget(f *File, line int) (Target, bool) {
+	var target Target
+	closer := func(pos Position) bool {
+		return pos.Line > line && (target.Kind == UnknownTarget || pos.Line < target.Line)
+	}
+	for n := range f.Types {
+		if closer(f.Types[n].Position) {
+			target = Target{Kind: TypeTarget, Position: f.Types[n].Position, Type: &f.Types[n]}
+		}
+	}
+	for n := range f.Funcs {
+		if closer(f.Funcs[n].Position) {
+			target = Target{Kind: FuncTarget, Position: f.Funcs[n].Position, Func: &f.Funcs[n]}
+		}
+	}
+	for _, decl := range f.Consts {
+		if closer(decl.Position) {
+			target = Target{Kind: ConstTarget, Position: decl.Position, Consts: []ConstDecl{decl}}
+		}
+	}
+	for _, decl := range f.Vars {
+		if closer(decl.Position) {
+			target = Target{Kind: VarTarget, Position: decl.Position, Vars: []VarDecl{decl}}
+		}
+	}
+	if target.Kind == UnknownTarget {
+		return Target{}, false
+	}
+	return completeTarget(f, target), true
+}
+
+// findNamedTarget finds the declaration in f with the given name. Methods are not considered.
+func findNamedTarget(f *File, name string) (Target, bool) {
+	for n := range f.Types {
+		if f.Types[n].Name == name {
+			return Target{Kind: TypeTarget, Position: f.Types[n].Position, Type: &f.Types[n]}, true
+		}
+	}
+	for n := range f.Funcs {
+		if f.Funcs[n].Name == name && f.Funcs[n].Recv == "" {
+			return Target{Kind: FuncTarget, Position: f.Funcs[n].Position, Func: &f.Funcs[n]}, true
+		}
+	}
+	for _, decl := range f.Consts {
+		if decl.Name == name {
+			return completeTarget(f, Target{Kind: ConstTarget, Position: decl.Position, Consts: []ConstDecl{decl}}), true
+		}
+	}
+	for _, decl := range f.Vars {
+		if decl.Name == name {
+			return completeTarget(f, Target{Kind: VarTarget, Position: decl.Position, Vars: []VarDecl{decl}}), true
+		}
+	}
+	return Target{}, false
+}
+
+// completeTarget extends a const target to its whole const block, and a var target to its whole var spec.
+func completeTarget(f *File, target Target) Target {
+	switch target.Kind {
+	case ConstTarget:
+		group := target.Consts[0].Group
+		target.Consts = nil
+		for _, decl := range f.Consts {
+			if decl.Group == group {
+				target.Consts = append(target.Consts, decl)
+			}
+		}
+		target.Position = target.Consts[0].Position
+	case VarTarget:
+		target.Vars = nil
+		for _, decl := range f.Vars {
+			if decl.Line == target.Line {
+				target.Vars = append(target.Vars, decl)
+			}
+		}
+	}
+	return target
+}
@@ -0,0 +1,84 @@
+package gadget
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInfo_Target(t *testing.T) {
+	src := `package test
+
+//go:generate gen
+
+// Documented is documented.
+type Documented int
+
+//go:generate gen
+type (
+	First  int
+	//go:generate gen
+	Second string
+)
+
+//go:generate gen
+func Hello() {}
+
+//go:generate gen
+const (
+	A = iota
+	B
+)
+
+//go:generate gen
+var X, Y int
+
+//go:generate gen
+`
+	f, err := NewFile("test.go", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("failed to parse file: %v", err)
+	}
+	for _, test := range []struct {
+		line int
+		kind TargetKind
+		name string
+		size int
+	}{
+		{line: 3, kind: TypeTarget, name: "Documented"},
+		{line: 8, kind: TypeTarget, name: "First"},
+		{line: 11, kind: TypeTarget, name: "Second"},
+		{line: 15, kind: FuncTarget, name: "Hello"},
+		{line: 18, kind: ConstTarget, name: "A", size: 2},
+		{line: 24, kind: VarTarget, name: "X", size: 2},
+	} {
+		info := &Info{File: "test.go", Line: test.line, file: f}
+		target, err := info.Target()
+		if err != nil {
+			t.Fatalf("line %d: failed to find target: %v", test.line, err)
+		}
+		if target.Kind != test.kind || target.Name() != test.name {
+			t.Fatalf("line %d: expected %s %s, got %s %s", test.line, test.kind, test.name, target.Kind, target.Name())
+		}
+		if size := len(target.Consts) + len(target.Vars); size != test.size {
+			t.Fatalf("line %d: expected %d constants or variables, got %d", test.line, test.size, size)
+		}
+	}
+
+	info := &Info{File: "test.go", Line: 27, file: f}
+	if _, err := info.Target(); err == nil || !strings.HasPrefix(err.Error(), "test.go:27: ") {
+		t.Fatalf("expected positioned error for directive without declaration, got %v", err)
+	}
+
+	info = &Info{File: "test.go", Line: 11, file: f}
+	name, typ, err := info.GetType()
+	if err != nil {
+		t.Fatalf("failed to get type: %v", err)
+	}
+	if name != "Second" || typ != String {
+		t.Fatalf("unexpected type %s %v", name, typ)
+	}
+	info = &Info{File: "test.go", Line: 15, file: f}
+	if _, _, err := info.GetType(); err == nil {
+		t.Fatalf("expected error for directive preceding a function")
+	}
+}
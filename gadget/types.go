@@ -36,7 +36,7 @@ var (
 )
 
 // Type is one of the following concrete types:
-// Ident, Pointer, Slice, Array, Map, Struct, Chan, Func, Interface, Selector
+// Ident, Pointer, Slice, Array, Map, Struct, Chan, Func, Interface, Selector, TypeParam, Instance, Union
 type Type interface {
 	String() string
 	isType()
@@ -83,10 +83,14 @@ func (s Slice) isType() {}
 
 type Array struct {
 	Elem Type
-	Size int
+	Size int    // The length of the array, or -1 if the length is given by Len.
+	Len  string // The length expression, if it could not be evaluated on its own. Like N, 2*N or ... for [...]T.
 }
 
 func (a Array) String() string {
+	if a.Len != "" {
+		return fmt.Sprintf("[%s]%s", a.Len, a.Elem.String())
+	}
 	return fmt.Sprintf("[%d]%s", a.Size, a.Elem.String())
 }
 
@@ -118,9 +122,20 @@ func (s Struct) String() string {
 func (s Struct) isType() {}
 
 type StructField struct {
-	Name string
-	Type Type
-	Tag  string
+	Position   // The position of the field. Zero if the field was not parsed from a file.
+	Name       string
+	Type       Type
+	Tag        string
+	Doc        string     // The doc comment of the field, without directives.
+	Directives Directives // The directives in the doc comment and line comment of the field.
+}
+
+// FieldName returns the name of the field, or the name an embedded field is accessed by, like T for *pkg.T or T[int].
+func (f StructField) FieldName() string {
+	if f.Name == "" {
+		return embeddedName(f.Type)
+	}
+	return f.Name
 }
 
 func (f StructField) String() string {
@@ -177,8 +192,9 @@ func (c Chan) String() string {
 func (c Chan) isType() {}
 
 type Func struct {
-	Params  []FuncParam
-	Results []FuncResult
+	Params   []FuncParam
+	Results  []FuncResult
+	Variadic bool // Variadic is true if the final parameter is variadic. Its Type is then a Slice of the element type.
 }
 
 func (f Func) String() string {
@@ -189,7 +205,12 @@ func (f Func) isType() {}
 
 func (f Func) toPrototype() string {
 	var params []string
-	for _, param := range f.Params {
+	for i, param := range f.Params {
+		if f.Variadic && i == len(f.Params)-1 {
+			if slice, ok := param.Type.(Slice); ok {
+				param.Type = Ident("..." + slice.Elem.String())
+			}
+		}
 		params = append(params, param.String())
 	}
 	full := fmt.Sprintf("(%s)", strings.Join(params, ", "))
@@ -232,12 +253,16 @@ func (f FuncResult) String() string {
 
 type Interface struct {
 	Methods []InterfaceMethod
+	Embeds  []Type // Embedded interfaces and type elements, like io.Reader or ~int | ~string.
 }
 
 func (i Interface) String() string {
-	fields := make([]string, len(i.Methods))
-	for i, field := range i.Methods {
-		fields[i] = field.String()
+	var fields []string
+	for _, embed := range i.Embeds {
+		fields = append(fields, embed.String())
+	}
+	for _, field := range i.Methods {
+		fields = append(fields, field.String())
 	}
 	return fmt.Sprintf("interface {%s}", strings.Join(fields, "; "))
 }
@@ -245,10 +270,79 @@ func (i Interface) String() string {
 func (i Interface) isType() {}
 
 type InterfaceMethod struct {
-	Name string
-	Type Func
+	Position   // The position of the method. Zero if the method was not parsed from a file.
+	Name       string
+	Type       Func
+	Doc        string     // The doc comment of the method, without directives.
+	Directives Directives // The directives in the doc comment and line comment of the method.
 }
 
 func (f InterfaceMethod) String() string {
 	return f.Name + f.Type.toPrototype()
 }
+
+// TypeParam is a reference to a type parameter in scope.
+type TypeParam string
+
+func (p TypeParam) String() string {
+	return string(p)
+}
+
+func (p TypeParam) isType() {}
+
+// Instance is an instantiated generic type, like List[int].
+type Instance struct {
+	Type Type   // The generic type. Is either Ident or Selector.
+	Args []Type // The type arguments.
+}
+
+func (i Instance) String() string {
+	args := make([]string, len(i.Args))
+	for n, arg := range i.Args {
+		args[n] = arg.String()
+	}
+	return fmt.Sprintf("%s[%s]", i.Type.String(), strings.Join(args, ", "))
+}
+
+func (i Instance) isType() {}
+
+// Union is a constraint type element, like ~int | string.
+// A single approximation term like ~int is a Union with one term.
+type Union struct {
+	Terms []Term
+}
+
+func (u Union) String() string {
+	terms := make([]string, len(u.Terms))
+	for i, term := range u.Terms {
+		terms[i] = term.String()
+	}
+	return strings.Join(terms, " | ")
+}
+
+func (u Union) isType() {}
+
+type Term struct {
+	Tilde bool // Tilde is true if the term is an approximation, like ~int.
+	Type  Type
+}
+
+func (t Term) String() string {
+	if t.Tilde {
+		return "~" + t.Type.String()
+	}
+	return t.Type.String()
+}
+
+// TypeParamDecl is a type parameter declared by a generic type or function.
+type TypeParamDecl struct {
+	Name       string
+	Constraint Type // The constraint of the type parameter. Nil for the type parameters of a method receiver.
+}
+
+func (p TypeParamDecl) String() string {
+	if p.Constraint == nil {
+		return p.Name
+	}
+	return p.Name + " " + p.Constraint.String()
+}
@@ -41,3 +41,24 @@ func TestTypeIs(t *testing.T) {
 		})
 	}
 }
+
+func TestStructField_FieldName(t *testing.T) {
+	for _, test := range []struct {
+		src      string
+		expected string
+	}{
+		{"struct{ A int }", "A"},
+		{"struct{ T }", "T"},
+		{"struct{ *pkg.T }", "T"},
+		{"struct{ List[int] }", "List"},
+		{"struct{ *pkg.List[int, string] }", "List"},
+	} {
+		typ, err := ParseType(test.src)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", test.src, err)
+		}
+		if name := typ.(Struct).Fields[0].FieldName(); name != test.expected {
+			t.Errorf("field name of %s: want %s, got %s", test.src, test.expected, name)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package gadget
+
+// Walk calls f for t and, if f returns true, for every type contained within t, depth first.
+// Contained types include the element types of composites, the types of struct fields,
+// function parameters and results, interface methods and embeds, type arguments and union terms.
+func Walk(t Type, f func(Type) bool) {
+	if t == nil || !f(t) {
+		return
+	}
+	switch t := t.(type) {
+	case Pointer:
+		Walk(t.Elem, f)
+	case Slice:
+		Walk(t.Elem, f)
+	case Array:
+		Walk(t.Elem, f)
+	case Map:
+		Walk(t.Key, f)
+		Walk(t.Value, f)
+	case Chan:
+		Walk(t.Elem, f)
+	case Struct:
+		for _, field := range t.Fields {
+			Walk(field.Type, f)
+		}
+	case Func:
+		for _, param := range t.Params {
+			Walk(param.Type, f)
+		}
+		for _, result := range t.Results {
+			Walk(result.Type, f)
+		}
+	case Interface:
+		for _, embed := range t.Embeds {
+			Walk(embed, f)
+		}
+		for _, method := range t.Methods {
+			Walk(method.Type, f)
+		}
+	case Instance:
+		Walk(t.Type, f)
+		for _, arg := range t.Args {
+			Walk(arg, f)
+		}
+	case Union:
+		for _, term := range t.Terms {
+			Walk(term.Type, f)
+		}
+	}
+}
+
+// Rewrite rebuilds t bottom-up, replacing every contained type by the result of f.
+// Composite types are rebuilt from their rewritten elements before being passed to f.
+// The signatures of interface methods are rebuilt but not passed to f themselves, as they must remain a Func.
+// t itself is not modified.
+func Rewrite(t Type, f func(Type) Type) Type {
+	if t == nil {
+		return nil
+	}
+	switch tt := t.(type) {
+	case Pointer:
+		t = Pointer{Elem: Rewrite(tt.Elem, f)}
+	case Slice:
+		t = Slice{Elem: Rewrite(tt.Elem, f)}
+	case Array:
+		tt.Elem = Rewrite(tt.Elem, f)
+		t = tt
+	case Map:
+		t = Map{Key: Rewrite(tt.Key, f), Value: Rewrite(tt.Value, f)}
+	case Chan:
+		t = Chan{Dir: tt.Dir, Elem: Rewrite(tt.Elem, f)}
+	case Struct:
+		t = rewriteStruct(tt, f)
+	case Func:
+		t = rewriteFunc(tt, f)
+	case Interface:
+		t = rewriteInterface(tt, f)
+	case Instance:
+		args := make([]Type, len(tt.Args))
+		for i, arg := range tt.Args {
+			args[i] = Rewrite(arg, f)
+		}
+		t = Instance{Type: Rewrite(tt.Type, f), Args: args}
+	case Union:
+		terms := make([]Term, len(tt.Terms))
+		for i, term := range tt.Terms {
+			terms[i] = Term{Tilde: term.Tilde, Type: Rewrite(term.Type, f)}
+		}
+		t = Union{Terms: terms}
+	}
+	return f(t)
+}
+
+func rewriteStruct(s Struct, f func(Type) Type) Struct {
+	if s.Fields == nil {
+		return s
+	}
+	fields := make([]StructField, len(s.Fields))
+	for i, field := range s.Fields {
+		field.Type = Rewrite(field.Type, f)
+		fields[i] = field
+	}
+	s.Fields = fields
+	return s
+}
+
+func rewriteFunc(fun Func, f func(Type) Type) Func {
+	if fun.Params != nil {
+		params := make([]FuncParam, len(fun.Params))
+		for i, param := range fun.Params {
+			param.Type = Rewrite(param.Type, f)
+			params[i] = param
+		}
+		fun.Params = params
+	}
+	if fun.Results != nil {
+		results := make([]FuncResult, len(fun.Results))
+		for i, result := range fun.Results {
+			result.Type = Rewrite(result.Type, f)
+			results[i] = result
+		}
+		fun.Results = results
+	}
+	return fun
+}
+
+func rewriteInterface(iface Interface, f func(Type) Type) Interface {
+	if iface.Embeds != nil {
+		embeds := make([]Type, len(iface.Embeds))
+		for i, embed := range iface.Embeds {
+			embeds[i] = Rewrite(embed, f)
+		}
+		iface.Embeds = embeds
+	}
+	if iface.Methods == nil {
+		return iface
+	}
+	methods := make([]InterfaceMethod, len(iface.Methods))
+	for i, method := range iface.Methods {
+		method.Type = rewriteFunc(method.Type, f)
+		methods[i] = method
+	}
+	iface.Methods = methods
+	return iface
+}
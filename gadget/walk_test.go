@@ -0,0 +1,62 @@
+package gadget
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	typ, err := ParseType("map[io.Reader]func(x []*bytes.Buffer, y chan<- struct{ D time.Duration }) (interface{ Read() io.Reader }, [2]List[fmt.Stringer])")
+	if err != nil {
+		t.Fatalf("failed to parse type: %v", err)
+	}
+	var selectors []Selector
+	Walk(typ, func(t Type) bool {
+		if sel, ok := t.(Selector); ok {
+			selectors = append(selectors, sel)
+		}
+		return true
+	})
+	expected := []Selector{
+		{Left: "io", Right: "Reader"},
+		{Left: "bytes", Right: "Buffer"},
+		{Left: "time", Right: "Duration"},
+		{Left: "io", Right: "Reader"},
+		{Left: "fmt", Right: "Stringer"},
+	}
+	if !reflect.DeepEqual(expected, selectors) {
+		t.Logf("want: %v", expected)
+		t.Logf(" got: %v", selectors)
+		t.Fatalf("invalid selectors")
+	}
+
+	count := 0
+	Walk(typ, func(t Type) bool {
+		count++
+		_, isFunc := t.(Func)
+		return !isFunc
+	})
+	if count != 3 {
+		t.Fatalf("expected Walk to visit map, key and value only, visited %d types", count)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	typ, err := ParseType("struct{ A []pkg.X; B map[string]*pkg.X; C func(pkg.X) pkg.Y }")
+	if err != nil {
+		t.Fatalf("failed to parse type: %v", err)
+	}
+	original, _ := ParseType(typ.String())
+	rewritten := Rewrite(typ, func(t Type) Type {
+		if t == (Selector{Left: "pkg", Right: "X"}) {
+			return Ident("Y")
+		}
+		return t
+	})
+	if s := rewritten.String(); s != "struct{A []Y; B map[string]*Y; C func(Y) pkg.Y}" {
+		t.Fatalf("unexpected rewritten type %s", s)
+	}
+	if !SameType(original, typ) {
+		t.Fatalf("Rewrite modified its input: %s", typ)
+	}
+}
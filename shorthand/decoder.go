@@ -123,7 +123,7 @@ func (d *Decoder) Bytes(field string, num int) []byte {
 	cop := make([]byte, num)
 	copy(cop, b)
 	d.Advance("Bytes", field, num)
-	return b
+	return cop
 }
 
 func (d *Decoder) ByteSlice(field string) []byte {
@@ -0,0 +1,26 @@
+package shorthand
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecoder_Bytes(t *testing.T) {
+	e := NewEncoder(nil)
+	e.ByteSlice([]byte("abc"))
+	e.String("def")
+	buf := e.Copy()
+	d := NewDecoder(buf)
+	got := d.ByteSlice("bytes")
+	if s := d.String("string"); s != "def" {
+		t.Fatalf("expected def, got %q", s)
+	}
+	for i := range buf {
+		buf[i] = 0
+	}
+	if !bytes.Equal(got, []byte("abc")) {
+		t.Logf("want: %q", "abc")
+		t.Logf(" got: %q", got)
+		t.Fatalf("decoded bytes share memory with the buffer")
+	}
+}